@@ -34,6 +34,7 @@ func main() {
 		SMName:             "oran-e2sm-mho",
 		SMVersion:          "v2",
 		TSPolicySchemePath: "/data/schemas/ORAN_TrafficSteeringPreference_v102.json",
+		ConfigPath:         "/etc/onos/config/config.json",
 	}
 
 	a1Config := a1.Config{
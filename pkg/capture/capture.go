@@ -0,0 +1,232 @@
+// SPDX-FileCopyrightText: 2019-present Open Networking Foundation <info@opennetworking.org>
+// SPDX-FileCopyrightText: 2019-present Rimedo Labs
+//
+// SPDX-License-Identifier: Apache-2.0
+// Created by RIMEDO-Labs team
+
+// Package capture records the E2 indications to a capture file and replays them, so that the steering can be
+// reproduced without a RAN.
+//
+// A capture file holds one JSON record per line, the header and the payload of the indication base64 encoded.
+package capture
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	e2sm_mho "github.com/onosproject/onos-e2-sm/servicemodels/e2sm_mho_go/v2/e2sm-mho-go"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"github.com/onosproject/rimedo-ts/pkg/mho"
+)
+
+var log = logging.GetLogger("rimedo-ts", "capture")
+
+// Record is an indication of a capture file.
+type Record struct {
+	NodeID string `json:"nodeId"`
+	// TriggerType is the name of the MHO trigger type of the subscription, e.g. MHO_TRIGGER_TYPE_PERIODIC
+	TriggerType string    `json:"triggerType"`
+	Timestamp   time.Time `json:"timestamp"`
+	Header      []byte    `json:"header"`
+	Payload     []byte    `json:"payload"`
+}
+
+// NewRecord converts the indication, an indication without a timestamp gets the current time.
+func NewRecord(indication *mho.E2NodeIndication) Record {
+	timestamp := indication.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	return Record{
+		NodeID:      indication.NodeID,
+		TriggerType: indication.TriggerType.String(),
+		Timestamp:   timestamp,
+		Header:      indication.IndMsg.Header,
+		Payload:     indication.IndMsg.Payload,
+	}
+}
+
+// Indication converts the record back to the indication.
+func (r Record) Indication() (*mho.E2NodeIndication, error) {
+	triggerType, ok := e2sm_mho.MhoTriggerType_value[r.TriggerType]
+	if !ok {
+		return nil, fmt.Errorf("unknown trigger type %v", r.TriggerType)
+	}
+	return &mho.E2NodeIndication{
+		NodeID:      r.NodeID,
+		TriggerType: e2sm_mho.MhoTriggerType(triggerType),
+		IndMsg: e2api.Indication{
+			Header:  r.Header,
+			Payload: r.Payload,
+		},
+		Timestamp: r.Timestamp,
+	}, nil
+}
+
+// NewRecorder returns a recorder writing to the capture file at the path, which is truncated.
+func NewRecorder(path string) (*Recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	writer := bufio.NewWriter(file)
+	return &Recorder{
+		file:    file,
+		writer:  writer,
+		encoder: json.NewEncoder(writer),
+	}, nil
+}
+
+// SameFile reports whether the paths name the same capture file, which can't be recorded to while it is replayed
+// since the recorder truncates it.
+func SameFile(path1 string, path2 string) bool {
+	abs1, err1 := filepath.Abs(path1)
+	abs2, err2 := filepath.Abs(path2)
+	if err1 == nil && err2 == nil && abs1 == abs2 {
+		return true
+	}
+	info1, err1 := os.Stat(path1)
+	info2, err2 := os.Stat(path2)
+	return err1 == nil && err2 == nil && os.SameFile(info1, info2)
+}
+
+// Recorder writes the indications to a capture file.
+type Recorder struct {
+	file    *os.File
+	writer  *bufio.Writer
+	encoder *json.Encoder
+	count   uint64
+	mu      sync.Mutex
+}
+
+// Record writes the indication to the capture file; it is flushed every second and when the context is done
+// by Run, and when closed.
+func (r *Recorder) Record(indication *mho.E2NodeIndication) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return fmt.Errorf("recorder is closed")
+	}
+	if err := r.encoder.Encode(NewRecord(indication)); err != nil {
+		return err
+	}
+	r.count++
+	return nil
+}
+
+// Run flushes the recorded indications every second, and a last time when the context is done.
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.Flush(); err != nil {
+				log.Warn(err)
+			}
+		case <-ctx.Done():
+			if err := r.Flush(); err != nil {
+				log.Warn(err)
+			}
+			return
+		}
+	}
+}
+
+// Flush writes the buffered indications to the capture file.
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	return r.writer.Flush()
+}
+
+// Count returns how many indications were recorded.
+func (r *Recorder) Count() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.count
+}
+
+// Close flushes and closes the capture file.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.writer.Flush()
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	r.file = nil
+	return err
+}
+
+// Replay sends the indications of the capture file at the path to the channel, keeping the intervals between them
+// divided by the speed: 1 replays at the original speed, 10 ten times faster and 0 as fast as the channel takes
+// them. The replayed indications get the time they are sent as timestamp. It returns how many indications were
+// sent, once the whole capture is replayed or the context is done.
+func Replay(ctx context.Context, path string, indChan chan<- *mho.E2NodeIndication, speed float64) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	return ReplayFrom(ctx, file, indChan, speed)
+}
+
+// ReplayFrom replays the capture read from the reader, see Replay.
+func ReplayFrom(ctx context.Context, reader io.Reader, indChan chan<- *mho.E2NodeIndication, speed float64) (int, error) {
+	if speed < 0 {
+		return 0, fmt.Errorf("invalid replay speed %v", speed)
+	}
+	decoder := json.NewDecoder(reader)
+	var first time.Time
+	start := time.Now()
+	sent := 0
+	for {
+		record := Record{}
+		if err := decoder.Decode(&record); err == io.EOF {
+			return sent, nil
+		} else if err != nil {
+			return sent, fmt.Errorf("invalid record %v of the capture: %v", sent+1, err)
+		}
+		indication, err := record.Indication()
+		if err != nil {
+			return sent, fmt.Errorf("invalid record %v of the capture: %v", sent+1, err)
+		}
+
+		if first.IsZero() {
+			first = record.Timestamp
+		}
+		if speed > 0 {
+			offset := time.Duration(float64(record.Timestamp.Sub(first)) / speed)
+			if wait := time.Until(start.Add(offset)); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+					return sent, ctx.Err()
+				}
+			}
+		}
+
+		indication.Timestamp = time.Now()
+		select {
+		case indChan <- indication:
+			sent++
+		case <-ctx.Done():
+			return sent, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2019-present Open Networking Foundation <info@opennetworking.org>
+// SPDX-FileCopyrightText: 2019-present Rimedo Labs
+//
+// SPDX-License-Identifier: Apache-2.0
+// Created by RIMEDO-Labs team
+
+package capture
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	e2sm_mho "github.com/onosproject/onos-e2-sm/servicemodels/e2sm_mho_go/v2/e2sm-mho-go"
+	"github.com/onosproject/rimedo-ts/pkg/mho"
+)
+
+func newIndications() []*mho.E2NodeIndication {
+	start := time.Now()
+	return []*mho.E2NodeIndication{
+		{
+			NodeID:      "e2:1/5153",
+			TriggerType: e2sm_mho.MhoTriggerType_MHO_TRIGGER_TYPE_PERIODIC,
+			IndMsg:      e2api.Indication{Header: []byte{0x01, 0x02}, Payload: []byte{0x03, 0x04, 0x05}},
+			Timestamp:   start,
+		},
+		{
+			NodeID:      "e2:1/5154",
+			TriggerType: e2sm_mho.MhoTriggerType_MHO_TRIGGER_TYPE_UPON_CHANGE_RRC_STATUS,
+			IndMsg:      e2api.Indication{Header: []byte{0x06}, Payload: []byte{}},
+			Timestamp:   start.Add(20 * time.Millisecond),
+		},
+	}
+}
+
+func replayAll(t *testing.T, path string, speed float64) []*mho.E2NodeIndication {
+	indChan := make(chan *mho.E2NodeIndication, 10)
+	sent, err := Replay(context.Background(), path, indChan, speed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(indChan)
+	replayed := make([]*mho.E2NodeIndication, 0, sent)
+	for indication := range indChan {
+		replayed = append(replayed, indication)
+	}
+	return replayed
+}
+
+func TestRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.json")
+	recorder, err := NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indications := newIndications()
+	for _, indication := range indications {
+		if err := recorder.Record(indication); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if count := recorder.Count(); count != uint64(len(indications)) {
+		t.Errorf("%v indications recorded, %v expected", count, len(indications))
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Record(indications[0]); err == nil {
+		t.Error("indication recorded after the recorder was closed")
+	}
+
+	start := time.Now()
+	replayed := replayAll(t, path, 1)
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("capture replayed in %v, the 20ms between the indications expected", elapsed)
+	}
+	if len(replayed) != len(indications) {
+		t.Fatalf("%v indications replayed, %v expected", len(replayed), len(indications))
+	}
+	for i, indication := range replayed {
+		expected := indications[i]
+		if indication.NodeID != expected.NodeID || indication.TriggerType != expected.TriggerType ||
+			!bytes.Equal(indication.IndMsg.Header, expected.IndMsg.Header) ||
+			!bytes.Equal(indication.IndMsg.Payload, expected.IndMsg.Payload) {
+			t.Errorf("indication %v replayed as %+v, %+v expected", i, indication, expected)
+		}
+		if indication.Timestamp.Before(start) {
+			t.Errorf("indication %v replayed with the recorded timestamp %v, the replay time expected", i, indication.Timestamp)
+		}
+	}
+}
+
+func TestRecorderFlushOnDone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.json")
+	recorder, err := NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recorder.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		recorder.Run(ctx)
+		close(done)
+	}()
+	for _, indication := range newIndications() {
+		if err := recorder.Record(indication); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cancel()
+	<-done
+
+	// the capture is complete without closing the recorder
+	if replayed := replayAll(t, path, 0); len(replayed) != 2 {
+		t.Errorf("%v indications replayed once the recorder context is done, 2 expected", len(replayed))
+	}
+}
+
+func TestReplayInvalid(t *testing.T) {
+	indChan := make(chan *mho.E2NodeIndication, 10)
+	if _, err := ReplayFrom(context.Background(), strings.NewReader(""), indChan, -1); err == nil {
+		t.Error("negative replay speed accepted")
+	}
+
+	capture := `{"nodeId":"e2:1/5153","triggerType":"MHO_TRIGGER_TYPE_PERIODIC","header":"AQI=","payload":"AwQF"}
+{"nodeId":"e2:1/5153","triggerType":"MHO_TRIGGER_TYPE_UNKNOWN"}
+`
+	sent, err := ReplayFrom(context.Background(), strings.NewReader(capture), indChan, 0)
+	if err == nil {
+		t.Error("record with an unknown trigger type replayed")
+	}
+	if sent != 1 {
+		t.Errorf("%v indications replayed before the invalid record, 1 expected", sent)
+	}
+}
@@ -0,0 +1,548 @@
+// SPDX-FileCopyrightText: 2019-present Open Networking Foundation <info@opennetworking.org>
+// SPDX-FileCopyrightText: 2019-present Rimedo Labs
+//
+// SPDX-License-Identifier: Apache-2.0
+// Created by RIMEDO-Labs team
+// based on onosproject/onos-mho/pkg/config/config.go
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	app "github.com/onosproject/onos-ric-sdk-go/pkg/config/app/default"
+	"github.com/onosproject/onos-ric-sdk-go/pkg/config/event"
+	configurable "github.com/onosproject/onos-ric-sdk-go/pkg/config/registry"
+	"github.com/onosproject/onos-ric-sdk-go/pkg/config/store"
+)
+
+var log = logging.GetLogger("rimedo-ts", "config")
+
+const (
+	ScoringConfigPath     = "/scoring"
+	HandoverConfigPath    = "/handover"
+	RateLimitConfigPath   = "/rateLimit"
+	LoadConfigPath        = "/load"
+	SteeringConfigPath    = "/steering"
+	ControlConfigPath     = "/control"
+	SlicesConfigPath      = "/slices"
+	TriggersConfigPath    = "/triggers"
+	IndicationsConfigPath = "/indications"
+	CaptureConfigPath     = "/capture"
+	PoliciesConfigPath    = "/policies"
+)
+
+// The default MHO triggers are top-level entries, as in the configuration of onos-mho
+const (
+	ReportingPeriodConfigPath     = "/reportingPeriod"
+	PeriodicConfigPath            = "/periodic"
+	UponRcvMeasReportConfigPath   = "/uponRcvMeasReport"
+	UponChangeRrcStatusConfigPath = "/uponChangeRrcStatus"
+	A3OffsetRangeConfigPath       = "/A3OffsetRange"
+	HysteresisRangeConfigPath     = "/HysteresisRange"
+)
+
+// Config xApp configuration interface
+type Config interface {
+	GetScoring() Scoring
+	GetSliceScoring() map[string]Scoring
+	GetHandover() Handover
+	GetRateLimit() RateLimit
+	GetLoad() Load
+	GetSteering() Steering
+	GetControl() Control
+	GetSlices() Slices
+	GetTriggers() TriggerConfig
+	GetIndications() Indications
+	GetCapture() Capture
+	GetPolicies() Policies
+	Watch(context.Context, chan event.Event) error
+}
+
+// NewConfig loads the xApp configuration from the JSON file and exposes it over gNMI,
+// so it can be changed while the xApp is running.
+func NewConfig(configPath string) (Config, error) {
+	appConfig, err := configurable.RegisterConfigurable(configPath, &configurable.RegisterRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tsConfig{
+		appConfig: appConfig.Config.(*app.Config),
+	}
+	return cfg, nil
+}
+
+// NewDefaultConfig returns a configuration without any entries, all values fall back to defaults.
+func NewDefaultConfig() Config {
+	return &tsConfig{
+		appConfig: app.NewConfig(store.NewConfigStore()),
+	}
+}
+
+type tsConfig struct {
+	appConfig *app.Config
+}
+
+// Watch watch config changes
+func (c *tsConfig) Watch(ctx context.Context, ch chan event.Event) error {
+	err := c.appConfig.Watch(ctx, ch)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Scoring holds the preference weights and the formula used to score candidate cells.
+type Scoring struct {
+	Function              string             `json:"function"`
+	RsrpCoefficient       float64            `json:"rsrpCoefficient"`
+	PreferenceCoefficient float64            `json:"preferenceCoefficient"`
+	LoadCoefficient       float64            `json:"loadCoefficient"`
+	Weights               map[string]float64 `json:"weights"`
+}
+
+// DefaultScoring returns the default weights. SHALL and FORBID are hard constraints
+// applied before scoring, so they have no weight.
+func DefaultScoring() Scoring {
+	return Scoring{
+		Function:              "additive",
+		RsrpCoefficient:       1.0,
+		PreferenceCoefficient: 1.0,
+		LoadCoefficient:       10.0,
+		Weights: map[string]float64{
+			"DEFAULT": 0.0,
+			"PREFER":  16.0,
+			"AVOID":   -16.0,
+		},
+	}
+}
+
+// Copy returns a deep copy of the scoring settings.
+func (s Scoring) Copy() Scoring {
+	weights := make(map[string]float64, len(s.Weights))
+	for k, v := range s.Weights {
+		weights[k] = v
+	}
+	s.Weights = weights
+	return s
+}
+
+// GetScoring gets the deployment-wide scoring, entries not configured keep their defaults
+func (c *tsConfig) GetScoring() Scoring {
+	scoring := DefaultScoring()
+	if err := c.decode(ScoringConfigPath, &scoring); err != nil {
+		log.Warn(err)
+		return DefaultScoring()
+	}
+	return scoring
+}
+
+// GetSliceScoring gets per-slice overrides keyed by "<sst>:<sd>" (or "<sst>" for slices without SD),
+// entries not configured for a slice are inherited from the deployment-wide scoring
+func (c *tsConfig) GetSliceScoring() map[string]Scoring {
+	output := make(map[string]Scoring)
+	raw := make(map[string]json.RawMessage)
+	if err := c.decode(ScoringConfigPath+"/slices", &raw); err != nil {
+		log.Warn(err)
+		return output
+	}
+	base := c.GetScoring()
+	for slice, value := range raw {
+		scoring := base.Copy()
+		if err := json.Unmarshal(value, &scoring); err != nil {
+			log.Warnf("Invalid scoring for slice %v: %v", slice, err)
+			continue
+		}
+		output[slice] = scoring
+	}
+	return output
+}
+
+// Handover holds the conditions a target cell has to meet before the UE is handed over to it.
+type Handover struct {
+	// Hysteresis is the margin, in dB of cell score, by which the target has to exceed the serving cell
+	Hysteresis float64 `json:"hysteresis"`
+	// TimeToTrigger is how long, in milliseconds, the target has to stay the best cell
+	TimeToTrigger uint64 `json:"timeToTrigger"`
+	// ConfirmationTimeout is how long, in milliseconds, a requested handover waits for an indication
+	// from the target cell before the UE is considered to be still in the source cell
+	ConfirmationTimeout uint64 `json:"confirmationTimeout"`
+}
+
+// DefaultConfirmationTimeout is the handover confirmation timeout, in milliseconds, if not configured
+const DefaultConfirmationTimeout = 5000
+
+// GetHandover gets the handover hysteresis, time-to-trigger and confirmation timeout
+func (c *tsConfig) GetHandover() Handover {
+	handover := Handover{
+		ConfirmationTimeout: DefaultConfirmationTimeout,
+	}
+	if err := c.decode(HandoverConfigPath, &handover); err != nil {
+		log.Warn(err)
+		return Handover{
+			ConfirmationTimeout: DefaultConfirmationTimeout,
+		}
+	}
+	return handover
+}
+
+// RateLimit holds the token-bucket limits of the handovers, a rate of 0 disables the limit.
+type RateLimit struct {
+	// UePerMinute is how many handovers per minute a single UE may get
+	UePerMinute float64 `json:"uePerMinute"`
+	// UeBurst is how many handovers a UE may get at once
+	UeBurst int `json:"ueBurst"`
+	// CellPerMinute is how many handovers per minute a single target cell may get
+	CellPerMinute float64 `json:"cellPerMinute"`
+	// CellBurst is how many handovers a target cell may get at once
+	CellBurst int `json:"cellBurst"`
+}
+
+// GetRateLimit gets the per-UE and per-cell handover limits
+func (c *tsConfig) GetRateLimit() RateLimit {
+	rateLimit := RateLimit{}
+	if err := c.decode(RateLimitConfigPath, &rateLimit); err != nil {
+		log.Warn(err)
+		return RateLimit{}
+	}
+	return rateLimit
+}
+
+// Load holds the capacity of the cells, in connected UEs. The capacity configured for the cell wins over
+// the one of its cell type, 0 means the load of the cell is not taken into account.
+type Load struct {
+	DefaultCapacity int            `json:"defaultCapacity"`
+	CellTypes       map[string]int `json:"cellTypes"`
+	Cells           map[string]int `json:"cells"`
+}
+
+// GetCapacity returns the capacity of the cell with the given CGI and cell type
+func (l Load) GetCapacity(cgi string, cellType string) int {
+	if capacity, ok := l.Cells[cgi]; ok {
+		return capacity
+	}
+	if capacity, ok := l.CellTypes[cellType]; ok {
+		return capacity
+	}
+	return l.DefaultCapacity
+}
+
+// GetLoad gets the cell capacities
+func (c *tsConfig) GetLoad() Load {
+	load := Load{}
+	if err := c.decode(LoadConfigPath, &load); err != nil {
+		log.Warn(err)
+		return Load{}
+	}
+	return load
+}
+
+// DefaultSweepInterval is how often, in milliseconds, all UEs are re-evaluated if not configured
+const DefaultSweepInterval = 10000
+
+// Steering selects the steering algorithm by its registered name; the parameters are passed to it as they are.
+type Steering struct {
+	Algorithm  string                 `json:"algorithm"`
+	Parameters map[string]interface{} `json:"parameters"`
+	// SweepInterval is how often, in milliseconds, all UEs are re-evaluated besides the evaluations
+	// triggered by measurement reports, RRC state changes and policy changes; 0 disables the sweep
+	SweepInterval uint64 `json:"sweepInterval"`
+}
+
+// GetSteering gets the steering algorithm, empty if not configured, and the sweep interval
+func (c *tsConfig) GetSteering() Steering {
+	steering := Steering{
+		SweepInterval: DefaultSweepInterval,
+	}
+	if err := c.decode(SteeringConfigPath, &steering); err != nil {
+		log.Warn(err)
+		return Steering{
+			SweepInterval: DefaultSweepInterval,
+		}
+	}
+	return steering
+}
+
+// Control holds how control requests failing for a transient cause are retried.
+type Control struct {
+	MaxRetries int `json:"maxRetries"`
+	// InitialBackoff is the delay, in milliseconds, before the first retry; it doubles after every retry
+	InitialBackoff uint64 `json:"initialBackoff"`
+	// MaxBackoff is the longest delay, in milliseconds, between the retries
+	MaxBackoff uint64 `json:"maxBackoff"`
+}
+
+// DefaultControl returns the default retries of the control requests
+func DefaultControl() Control {
+	return Control{
+		MaxRetries:     3,
+		InitialBackoff: 100,
+		MaxBackoff:     2000,
+	}
+}
+
+// GetControl gets the retries of the control requests
+func (c *tsConfig) GetControl() Control {
+	control := DefaultControl()
+	if err := c.decode(ControlConfigPath, &control); err != nil {
+		log.Warn(err)
+		return DefaultControl()
+	}
+	return control
+}
+
+// Slice is the S-NSSAI and the PLMN of a slice.
+type Slice struct {
+	Sst int64  `json:"sst"`
+	SD  string `json:"sd"`
+	Mcc string `json:"mcc"`
+	Mnc string `json:"mnc"`
+}
+
+// Slices maps the UEs to their slices. The slice configured for the UE wins over the one of its serving cell,
+// then of its E2 node, then the default one; a UE without a slice matches no slice-scoped policy.
+type Slices struct {
+	Default *Slice           `json:"default"`
+	Nodes   map[string]Slice `json:"nodes"`
+	Cells   map[string]Slice `json:"cells"`
+	Ues     map[string]Slice `json:"ues"`
+}
+
+// GetSlice returns the slice of the UE, the UE ID may be given with or without the leading zeros
+func (s Slices) GetSlice(ueID string, nodeID string, cgi string) *Slice {
+	if slice, ok := s.Ues[ueID]; ok {
+		return &slice
+	}
+	if slice, ok := s.Ues[strings.TrimLeft(ueID, "0")]; ok {
+		return &slice
+	}
+	if slice, ok := s.Cells[cgi]; ok {
+		return &slice
+	}
+	if slice, ok := s.Nodes[nodeID]; ok {
+		return &slice
+	}
+	return s.Default
+}
+
+// GetSlices gets the mapping of the UEs to the slices
+func (c *tsConfig) GetSlices() Slices {
+	slices := Slices{}
+	if err := c.decode(SlicesConfigPath, &slices); err != nil {
+		log.Warn(err)
+		return Slices{}
+	}
+	return slices
+}
+
+// DefaultReportingPeriod is the period, in milliseconds, of the periodic reports if not configured
+const DefaultReportingPeriod = 1000
+
+// Triggers selects the MHO triggers subscribed on an E2 node and the period of its periodic reports.
+type Triggers struct {
+	// ReportingPeriod is the period, in milliseconds, of the periodic reports
+	ReportingPeriod     uint64 `json:"reportingPeriod"`
+	Periodic            bool   `json:"periodic"`
+	UponRcvMeasReport   bool   `json:"uponRcvMeasReport"`
+	UponChangeRrcStatus bool   `json:"uponChangeRrcStatus"`
+	// A3OffsetRange and HysteresisRange are the A3 event parameters of onos-mho; the E2SM-MHO event
+	// trigger has no field for them, they are only reported in the state
+	A3OffsetRange   uint64 `json:"A3OffsetRange"`
+	HysteresisRange uint64 `json:"HysteresisRange"`
+}
+
+// DefaultTriggers returns the triggers of the nodes if not configured: all of them, reporting every second
+func DefaultTriggers() Triggers {
+	return Triggers{
+		ReportingPeriod:     DefaultReportingPeriod,
+		Periodic:            true,
+		UponRcvMeasReport:   true,
+		UponChangeRrcStatus: true,
+	}
+}
+
+// TriggersOverride overrides the triggers of some E2 nodes, entries not given are inherited.
+type TriggersOverride struct {
+	ReportingPeriod     *uint64 `json:"reportingPeriod"`
+	Periodic            *bool   `json:"periodic"`
+	UponRcvMeasReport   *bool   `json:"uponRcvMeasReport"`
+	UponChangeRrcStatus *bool   `json:"uponChangeRrcStatus"`
+	A3OffsetRange       *uint64 `json:"A3OffsetRange"`
+	HysteresisRange     *uint64 `json:"HysteresisRange"`
+}
+
+func (o TriggersOverride) apply(triggers *Triggers) {
+	if o.ReportingPeriod != nil {
+		triggers.ReportingPeriod = *o.ReportingPeriod
+	}
+	if o.Periodic != nil {
+		triggers.Periodic = *o.Periodic
+	}
+	if o.UponRcvMeasReport != nil {
+		triggers.UponRcvMeasReport = *o.UponRcvMeasReport
+	}
+	if o.UponChangeRrcStatus != nil {
+		triggers.UponChangeRrcStatus = *o.UponChangeRrcStatus
+	}
+	if o.A3OffsetRange != nil {
+		triggers.A3OffsetRange = *o.A3OffsetRange
+	}
+	if o.HysteresisRange != nil {
+		triggers.HysteresisRange = *o.HysteresisRange
+	}
+}
+
+// TriggerConfig holds the default triggers and their overrides per node label, keyed by "<key>=<value>",
+// and per E2 node ID. The overrides of the node win over the ones of its labels, which are applied in the
+// order of their keys.
+type TriggerConfig struct {
+	Default Triggers                    `json:"default"`
+	Labels  map[string]TriggersOverride `json:"labels"`
+	Nodes   map[string]TriggersOverride `json:"nodes"`
+}
+
+// GetTriggers returns the triggers of the E2 node with the given ID and labels; a reporting period of 0 is
+// replaced by the default one
+func (t TriggerConfig) GetTriggers(nodeID string, labels map[string]string) Triggers {
+	triggers := t.Default
+	keys := make([]string, 0, len(t.Labels))
+	for key := range t.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		parts := strings.SplitN(key, "=", 2)
+		if value, ok := labels[parts[0]]; ok && len(parts) == 2 && value == parts[1] {
+			t.Labels[key].apply(&triggers)
+		}
+	}
+	if override, ok := t.Nodes[nodeID]; ok {
+		override.apply(&triggers)
+	}
+	if triggers.ReportingPeriod == 0 {
+		triggers.ReportingPeriod = DefaultReportingPeriod
+	}
+	return triggers
+}
+
+// GetTriggers gets the default triggers from the top-level entries and their overrides
+func (c *tsConfig) GetTriggers() TriggerConfig {
+	triggers := TriggerConfig{
+		Default: DefaultTriggers(),
+	}
+	for path, out := range map[string]interface{}{
+		ReportingPeriodConfigPath:     &triggers.Default.ReportingPeriod,
+		PeriodicConfigPath:            &triggers.Default.Periodic,
+		UponRcvMeasReportConfigPath:   &triggers.Default.UponRcvMeasReport,
+		UponChangeRrcStatusConfigPath: &triggers.Default.UponChangeRrcStatus,
+		A3OffsetRangeConfigPath:       &triggers.Default.A3OffsetRange,
+		HysteresisRangeConfigPath:     &triggers.Default.HysteresisRange,
+	} {
+		if err := c.decode(path, out); err != nil {
+			log.Warnf("Invalid %v: %v", path, err)
+		}
+	}
+	overrides := TriggerConfig{}
+	if err := c.decode(TriggersConfigPath, &overrides); err != nil {
+		log.Warn(err)
+		return triggers
+	}
+	triggers.Labels = overrides.Labels
+	triggers.Nodes = overrides.Nodes
+	return triggers
+}
+
+// Indications sizes the processing of the E2 indications: they are buffered in the input queue, then handled by
+// the workers, the indications of a UE always by the same worker in the order they were received. A size of 0 is
+// replaced by the default.
+type Indications struct {
+	// Workers is how many indications are handled in parallel
+	Workers int `json:"workers"`
+	// InputQueueSize is how many received indications wait to be dispatched to the workers
+	InputQueueSize int `json:"inputQueueSize"`
+	// WorkerQueueSize is how many indications wait to be handled by a worker
+	WorkerQueueSize int `json:"workerQueueSize"`
+	// Overflow is what happens to an indication for a full worker queue: "block" waits, slowing down the E2
+	// indication streams, "drop-newest" drops the indication and "drop-oldest" the oldest one queued
+	Overflow string `json:"overflow"`
+}
+
+// DefaultIndications returns the processing of the indications if not configured
+func DefaultIndications() Indications {
+	return Indications{
+		Workers:         8,
+		InputQueueSize:  1024,
+		WorkerQueueSize: 128,
+		Overflow:        "block",
+	}
+}
+
+// GetIndications gets the processing of the indications; the sizes are only applied at startup
+func (c *tsConfig) GetIndications() Indications {
+	indications := DefaultIndications()
+	if err := c.decode(IndicationsConfigPath, &indications); err != nil {
+		log.Warn(err)
+		return DefaultIndications()
+	}
+	return indications
+}
+
+// Capture records the received indications to a capture file and replays a capture file.
+type Capture struct {
+	// Record is the path of the capture file the indications are written to, none are recorded if empty
+	Record string `json:"record"`
+	// Replay is the path of the capture file replayed at startup, none is replayed if empty
+	Replay string `json:"replay"`
+	// ReplaySpeed divides the intervals between the replayed indications, 0 replays them without waiting
+	ReplaySpeed float64 `json:"replaySpeed"`
+}
+
+// GetCapture gets the recording and the replay of the indications, only applied at startup
+func (c *tsConfig) GetCapture() Capture {
+	capture := Capture{
+		ReplaySpeed: 1,
+	}
+	if err := c.decode(CaptureConfigPath, &capture); err != nil {
+		log.Warn(err)
+		return Capture{
+			ReplaySpeed: 1,
+		}
+	}
+	return capture
+}
+
+// Policies holds the priorities of the A1 policies, keyed by policy ID. Of two overlapping policies with the same
+// scope rank the one with the higher priority wins; a policy not listed has priority 0.
+type Policies struct {
+	Priorities map[string]int `json:"priorities"`
+}
+
+// GetPolicies gets the priorities of the policies
+func (c *tsConfig) GetPolicies() Policies {
+	policies := Policies{}
+	if err := c.decode(PoliciesConfigPath, &policies); err != nil {
+		log.Warn(err)
+		return Policies{}
+	}
+	return policies
+}
+
+// decode unmarshals the configuration subtree under the path into out; a missing path leaves out untouched
+func (c *tsConfig) decode(path string, out interface{}) error {
+	entry, err := c.appConfig.Get(path)
+	if err != nil || entry.Value == nil {
+		return nil
+	}
+	bytes, err := json.Marshal(entry.Value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes, out)
+}
+
+var _ Config = &tsConfig{}
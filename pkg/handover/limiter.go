@@ -0,0 +1,173 @@
+// SPDX-FileCopyrightText: 2019-present Open Networking Foundation <info@opennetworking.org>
+// SPDX-FileCopyrightText: 2019-present Rimedo Labs
+//
+// SPDX-License-Identifier: Apache-2.0
+// Created by RIMEDO-Labs team
+
+package handover
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// NewLimiter creates token-bucket limits of the handovers per UE and per target cell.
+// A rate of 0 means no limit.
+func NewLimiter(ueRate float64, ueBurst int, cellRate float64, cellBurst int) *Limiter {
+	return &Limiter{
+		ueRate:     ueRate,
+		ueBurst:    ueBurst,
+		cellRate:   cellRate,
+		cellBurst:  cellBurst,
+		ues:        make(map[string]*bucket),
+		cells:      make(map[string]*bucket),
+		ueCounts:   make(map[string]uint64),
+		cellCounts: make(map[string]uint64),
+		mu:         sync.RWMutex{},
+	}
+}
+
+// Limiter keeps a token bucket per UE and per target cell, so a burst of decisions cannot cause a handover storm.
+type Limiter struct {
+	ueRate     float64
+	ueBurst    int
+	cellRate   float64
+	cellBurst  int
+	ues        map[string]*bucket
+	cells      map[string]*bucket
+	ueCounts   map[string]uint64
+	cellCounts map[string]uint64
+	mu         sync.RWMutex
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// LimiterStats counts the handover decisions suppressed by the limits.
+type LimiterStats struct {
+	UePerMinute       float64           `json:"uePerMinute"`
+	UeBurst           int               `json:"ueBurst"`
+	CellPerMinute     float64           `json:"cellPerMinute"`
+	CellBurst         int               `json:"cellBurst"`
+	SuppressedPerUe   map[string]uint64 `json:"suppressedPerUe"`
+	SuppressedPerCell map[string]uint64 `json:"suppressedPerCell"`
+}
+
+// SetParameters changes the limits; rates are handovers per minute.
+func (l *Limiter) SetParameters(ueRate float64, ueBurst int, cellRate float64, cellBurst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.ueRate, l.ueBurst, l.cellRate, l.cellBurst = ueRate, ueBurst, cellRate, cellBurst
+}
+
+// Reservation holds the tokens taken for a handover, so that they can be refunded if it isn't executed.
+type Reservation struct {
+	ueID      string
+	targetCGI string
+	ue        bool
+	cell      bool
+}
+
+// Reserve takes a token from the buckets of the UE and of the target cell. The handover is allowed only if
+// both have one; otherwise nothing is taken, the suppression is counted and the reason returned. The tokens
+// are to be refunded with Refund if the handover is not executed.
+func (l *Limiter) Reserve(ueID string, targetCGI string, now time.Time) (Reservation, bool, string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ueBucket := refill(l.ues, ueID, l.ueRate, l.ueBurst, now)
+	cellBucket := refill(l.cells, targetCGI, l.cellRate, l.cellBurst, now)
+
+	if ueBucket != nil && ueBucket.tokens < 1 {
+		l.ueCounts[ueID]++
+		return Reservation{}, false, fmt.Sprintf("UE exceeded %v handovers per minute", l.ueRate)
+	}
+	if cellBucket != nil && cellBucket.tokens < 1 {
+		l.cellCounts[targetCGI]++
+		return Reservation{}, false, fmt.Sprintf("target CELL exceeded %v handovers per minute", l.cellRate)
+	}
+	reservation := Reservation{
+		ueID:      ueID,
+		targetCGI: targetCGI,
+	}
+	if ueBucket != nil {
+		ueBucket.tokens--
+		reservation.ue = true
+	}
+	if cellBucket != nil {
+		cellBucket.tokens--
+		reservation.cell = true
+	}
+	return reservation, true, ""
+}
+
+// Refund gives the tokens of the reservation back to the buckets, up to their burst. The buckets forgotten or
+// disabled meanwhile are left alone.
+func (l *Limiter) Refund(reservation Reservation) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b, ok := l.ues[reservation.ueID]; ok && reservation.ue {
+		b.tokens = math.Min(math.Max(float64(l.ueBurst), 1), b.tokens+1)
+	}
+	if b, ok := l.cells[reservation.targetCGI]; ok && reservation.cell {
+		b.tokens = math.Min(math.Max(float64(l.cellBurst), 1), b.tokens+1)
+	}
+}
+
+func refill(buckets map[string]*bucket, key string, ratePerMinute float64, burst int, now time.Time) *bucket {
+	if ratePerMinute <= 0 {
+		delete(buckets, key)
+		return nil
+	}
+	capacity := math.Max(float64(burst), 1)
+	b, ok := buckets[key]
+	if !ok {
+		b = &bucket{
+			tokens: capacity,
+			last:   now,
+		}
+		buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Minutes()*ratePerMinute)
+	b.last = now
+	return b
+}
+
+// GetStats returns the limits with a copy of the counters of the suppressed handovers per UE and per cell.
+func (l *Limiter) GetStats() LimiterStats {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	stats := LimiterStats{
+		UePerMinute:       l.ueRate,
+		UeBurst:           l.ueBurst,
+		CellPerMinute:     l.cellRate,
+		CellBurst:         l.cellBurst,
+		SuppressedPerUe:   make(map[string]uint64, len(l.ueCounts)),
+		SuppressedPerCell: make(map[string]uint64, len(l.cellCounts)),
+	}
+	for k, v := range l.ueCounts {
+		stats.SuppressedPerUe[k] = v
+	}
+	for k, v := range l.cellCounts {
+		stats.SuppressedPerCell[k] = v
+	}
+	return stats
+}
+
+// Forget drops the bucket of the UE, suppression counters are kept.
+func (l *Limiter) Forget(ueID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.ues, ueID)
+}
+
+// ForgetCell drops the bucket of the removed cell, suppression counters are kept.
+func (l *Limiter) ForgetCell(cgi string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.cells, cgi)
+}
@@ -0,0 +1,129 @@
+// SPDX-FileCopyrightText: 2019-present Open Networking Foundation <info@opennetworking.org>
+// SPDX-FileCopyrightText: 2019-present Rimedo Labs
+//
+// SPDX-License-Identifier: Apache-2.0
+// Created by RIMEDO-Labs team
+
+package handover
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterRefill(t *testing.T) {
+	// 6 handovers per minute, one token every 10 seconds, up to 2
+	limiter := NewLimiter(6, 2, 0, 0)
+	start := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if _, ok, reason := limiter.Reserve("ue", "cell", start); !ok {
+			t.Fatalf("handover %v of the burst suppressed: %v", i+1, reason)
+		}
+	}
+	if _, ok, _ := limiter.Reserve("ue", "cell", start.Add(9*time.Second)); ok {
+		t.Fatal("handover allowed beyond the burst before a token was refilled")
+	}
+	if _, ok, reason := limiter.Reserve("ue", "cell", start.Add(11*time.Second)); !ok {
+		t.Fatalf("handover suppressed after a token was refilled: %v", reason)
+	}
+
+	// the bucket doesn't refill beyond the burst
+	later := start.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		if _, ok, reason := limiter.Reserve("ue", "cell", later); !ok {
+			t.Fatalf("handover %v of the refilled burst suppressed: %v", i+1, reason)
+		}
+	}
+	if _, ok, _ := limiter.Reserve("ue", "cell", later); ok {
+		t.Fatal("handover allowed beyond the refilled burst")
+	}
+
+	if stats := limiter.GetStats(); stats.SuppressedPerUe["ue"] != 2 {
+		t.Errorf("%v suppressed handovers of the UE counted, 2 expected", stats.SuppressedPerUe["ue"])
+	}
+}
+
+func TestLimiterCell(t *testing.T) {
+	limiter := NewLimiter(0, 0, 60, 1)
+	now := time.Now()
+
+	if _, ok, reason := limiter.Reserve("ue1", "cell", now); !ok {
+		t.Fatalf("handover suppressed: %v", reason)
+	}
+	if _, ok, _ := limiter.Reserve("ue2", "cell", now); ok {
+		t.Fatal("handover allowed beyond the burst of the cell")
+	}
+	if _, ok, reason := limiter.Reserve("ue2", "other", now); !ok {
+		t.Fatalf("handover to another cell suppressed: %v", reason)
+	}
+	if stats := limiter.GetStats(); stats.SuppressedPerCell["cell"] != 1 || len(stats.SuppressedPerUe) != 0 {
+		t.Errorf("suppressed handovers %v per cell and %v per UE, 1 for the cell expected", stats.SuppressedPerCell, stats.SuppressedPerUe)
+	}
+}
+
+func TestLimiterSuppressedTakesNothing(t *testing.T) {
+	limiter := NewLimiter(60, 1, 60, 1)
+	now := time.Now()
+
+	if _, ok, reason := limiter.Reserve("ue1", "cell", now); !ok {
+		t.Fatalf("handover suppressed: %v", reason)
+	}
+	// the cell is exhausted, the token of ue2 must not be taken
+	if _, ok, _ := limiter.Reserve("ue2", "cell", now); ok {
+		t.Fatal("handover allowed beyond the burst of the cell")
+	}
+	if _, ok, reason := limiter.Reserve("ue2", "other", now); !ok {
+		t.Fatalf("token of the UE taken by a suppressed handover: %v", reason)
+	}
+}
+
+func TestLimiterRefund(t *testing.T) {
+	limiter := NewLimiter(6, 1, 6, 1)
+	now := time.Now()
+
+	reservation, ok, reason := limiter.Reserve("ue", "cell", now)
+	if !ok {
+		t.Fatalf("handover suppressed: %v", reason)
+	}
+	limiter.Refund(reservation)
+	reservation, ok, reason = limiter.Reserve("ue", "cell", now)
+	if !ok {
+		t.Fatalf("handover suppressed after the refund: %v", reason)
+	}
+
+	// the refund never exceeds the burst
+	limiter.Refund(reservation)
+	limiter.Refund(reservation)
+	if _, ok, reason := limiter.Reserve("ue", "cell", now); !ok {
+		t.Fatalf("handover suppressed after the refund: %v", reason)
+	}
+	if _, ok, _ := limiter.Reserve("ue", "cell", now); ok {
+		t.Fatal("handover allowed beyond the burst after refunding twice")
+	}
+
+	// the refund of a forgotten UE is dropped
+	limiter.Forget("ue")
+	limiter.Refund(reservation)
+	if _, ok, reason := limiter.Reserve("ue", "other", now); !ok {
+		t.Fatalf("handover of the forgotten UE suppressed: %v", reason)
+	}
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	limiter := NewLimiter(0, 0, 0, 0)
+	now := time.Now()
+
+	for i := 0; i < 100; i++ {
+		reservation, ok, reason := limiter.Reserve("ue", "cell", now)
+		if !ok {
+			t.Fatalf("handover suppressed without limits: %v", reason)
+		}
+		limiter.Refund(reservation)
+	}
+	limiter.SetParameters(60, 1, 0, 0)
+	limiter.Reserve("ue", "cell", now)
+	if _, ok, _ := limiter.Reserve("ue", "cell", now); ok {
+		t.Fatal("handover allowed beyond the burst once the limit is enabled")
+	}
+}
@@ -0,0 +1,126 @@
+// SPDX-FileCopyrightText: 2019-present Open Networking Foundation <info@opennetworking.org>
+// SPDX-FileCopyrightText: 2019-present Rimedo Labs
+//
+// SPDX-License-Identifier: Apache-2.0
+// Created by RIMEDO-Labs team
+
+package handover
+
+import (
+	"sync"
+	"time"
+
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+)
+
+var log = logging.GetLogger("rimedo-ts", "handover")
+
+// NewTrigger creates the per-UE hysteresis and time-to-trigger evaluation.
+func NewTrigger(hysteresis float64, timeToTrigger time.Duration) *Trigger {
+	return &Trigger{
+		hysteresis:    hysteresis,
+		timeToTrigger: timeToTrigger,
+		candidates:    make(map[string]*candidate),
+		mu:            sync.RWMutex{},
+	}
+}
+
+// Trigger decides whether a better cell has been better for long enough to hand the UE over to it.
+type Trigger struct {
+	hysteresis    float64
+	timeToTrigger time.Duration
+	candidates    map[string]*candidate
+	mu            sync.RWMutex
+}
+
+type candidate struct {
+	targetCGI string
+	since     time.Time
+}
+
+// SetParameters changes the hysteresis (in dB of score) and the time-to-trigger.
+func (t *Trigger) SetParameters(hysteresis float64, timeToTrigger time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.hysteresis = hysteresis
+	t.timeToTrigger = timeToTrigger
+}
+
+// GetParameters returns the hysteresis (in dB of score) and the time-to-trigger.
+func (t *Trigger) GetParameters() (float64, time.Duration) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.hysteresis, t.timeToTrigger
+}
+
+// Check returns true when the UE should be handed over from the serving to the target cell. The target
+// has to score more than the hysteresis above the serving cell, continuously for the time-to-trigger.
+// If the serving cell isn't allowed for the UE any more (servingAllowed is false) the hysteresis is not
+// applied and the handover is triggered at once.
+func (t *Trigger) Check(ueID string, servingCGI string, targetCGI string, servingScore float64, targetScore float64, servingAllowed bool, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if servingCGI == targetCGI {
+		delete(t.candidates, ueID)
+		return false
+	}
+
+	if !servingAllowed {
+		delete(t.candidates, ueID)
+		return true
+	}
+
+	if targetScore <= servingScore+t.hysteresis {
+		if _, ok := t.candidates[ueID]; ok {
+			log.Debugf("UE [ID:%v] CELL [CGI:%v] no longer exceeds the hysteresis, time-to-trigger reset", ueID, targetCGI)
+		}
+		delete(t.candidates, ueID)
+		return false
+	}
+
+	c, ok := t.candidates[ueID]
+	if !ok || c.targetCGI != targetCGI {
+		c = &candidate{
+			targetCGI: targetCGI,
+			since:     now,
+		}
+		t.candidates[ueID] = c
+	}
+
+	if now.Sub(c.since) < t.timeToTrigger {
+		return false
+	}
+
+	delete(t.candidates, ueID)
+	return true
+}
+
+// Deadline returns when the time-to-trigger of the pending target cell of the UE expires.
+func (t *Trigger) Deadline(ueID string) (time.Time, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	c, ok := t.candidates[ueID]
+	if !ok {
+		return time.Time{}, false
+	}
+	return c.since.Add(t.timeToTrigger), true
+}
+
+// Forget drops the state kept for the UE.
+func (t *Trigger) Forget(ueID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.candidates, ueID)
+}
+
+// ForgetCell drops the pending handovers to the removed cell.
+func (t *Trigger) ForgetCell(cgi string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for ueID, c := range t.candidates {
+		if c.targetCGI == cgi {
+			delete(t.candidates, ueID)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// SPDX-FileCopyrightText: 2019-present Open Networking Foundation <info@opennetworking.org>
+// SPDX-FileCopyrightText: 2019-present Rimedo Labs
+//
+// SPDX-License-Identifier: Apache-2.0
+// Created by RIMEDO-Labs team
+
+package handover
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTriggerTimeToTrigger(t *testing.T) {
+	trigger := NewTrigger(3, 100*time.Millisecond)
+	start := time.Now()
+
+	if trigger.Check("ue", "serving", "target", -90, -85, true, start) {
+		t.Fatal("handover triggered before the time-to-trigger")
+	}
+	deadline, ok := trigger.Deadline("ue")
+	if !ok || !deadline.Equal(start.Add(100*time.Millisecond)) {
+		t.Fatalf("deadline %v, %v expected", deadline, start.Add(100*time.Millisecond))
+	}
+	if trigger.Check("ue", "serving", "target", -90, -85, true, start.Add(99*time.Millisecond)) {
+		t.Fatal("handover triggered before the time-to-trigger")
+	}
+	if !trigger.Check("ue", "serving", "target", -90, -85, true, start.Add(100*time.Millisecond)) {
+		t.Fatal("handover not triggered after the time-to-trigger")
+	}
+	if _, ok := trigger.Deadline("ue"); ok {
+		t.Error("target still pending after the handover was triggered")
+	}
+}
+
+func TestTriggerHysteresis(t *testing.T) {
+	trigger := NewTrigger(3, 0)
+	now := time.Now()
+
+	if trigger.Check("ue", "serving", "target", -90, -87, true, now) {
+		t.Error("handover triggered with the target at the hysteresis")
+	}
+	if !trigger.Check("ue", "serving", "target", -90, -86.5, true, now) {
+		t.Error("handover not triggered with the target above the hysteresis")
+	}
+	if trigger.Check("ue", "serving", "serving", -90, -90, true, now) {
+		t.Error("handover triggered to the serving cell")
+	}
+}
+
+func TestTriggerReset(t *testing.T) {
+	trigger := NewTrigger(3, 100*time.Millisecond)
+	start := time.Now()
+
+	trigger.Check("ue", "serving", "target", -90, -85, true, start)
+	// the target falls back within the hysteresis, the time-to-trigger starts over
+	if trigger.Check("ue", "serving", "target", -90, -88, true, start.Add(50*time.Millisecond)) {
+		t.Fatal("handover triggered within the hysteresis")
+	}
+	if trigger.Check("ue", "serving", "target", -90, -85, true, start.Add(100*time.Millisecond)) {
+		t.Fatal("handover triggered before the restarted time-to-trigger")
+	}
+	if !trigger.Check("ue", "serving", "target", -90, -85, true, start.Add(200*time.Millisecond)) {
+		t.Fatal("handover not triggered after the restarted time-to-trigger")
+	}
+
+	// another target starts over as well
+	trigger.Check("ue", "serving", "target", -90, -85, true, start)
+	if trigger.Check("ue", "serving", "other", -90, -85, true, start.Add(100*time.Millisecond)) {
+		t.Fatal("handover to another target triggered with the time-to-trigger of the previous one")
+	}
+	if !trigger.Check("ue", "serving", "other", -90, -85, true, start.Add(200*time.Millisecond)) {
+		t.Fatal("handover to another target not triggered after its time-to-trigger")
+	}
+}
+
+func TestTriggerServingNotAllowed(t *testing.T) {
+	trigger := NewTrigger(3, time.Second)
+	now := time.Now()
+
+	if !trigger.Check("ue", "serving", "target", math.Inf(-1), -100, false, now) {
+		t.Error("handover not triggered at once from a cell the UE isn't allowed in")
+	}
+}
+
+func TestTriggerForget(t *testing.T) {
+	trigger := NewTrigger(3, time.Second)
+	now := time.Now()
+
+	trigger.Check("ue1", "serving", "target", -90, -85, true, now)
+	trigger.Check("ue2", "serving", "other", -90, -85, true, now)
+	trigger.ForgetCell("target")
+	if _, ok := trigger.Deadline("ue1"); ok {
+		t.Error("handover to the removed cell still pending")
+	}
+	if _, ok := trigger.Deadline("ue2"); !ok {
+		t.Error("handover to another cell dropped with the removed cell")
+	}
+	trigger.Forget("ue2")
+	if _, ok := trigger.Deadline("ue2"); ok {
+		t.Error("handover of the forgotten UE still pending")
+	}
+}
+
+func TestTriggerParameters(t *testing.T) {
+	trigger := NewTrigger(3, time.Second)
+	trigger.SetParameters(1.5, 200*time.Millisecond)
+	if hysteresis, timeToTrigger := trigger.GetParameters(); hysteresis != 1.5 || timeToTrigger != 200*time.Millisecond {
+		t.Errorf("parameters %v and %v, 1.5 and 200ms expected", hysteresis, timeToTrigger)
+	}
+}
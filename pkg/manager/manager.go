@@ -9,6 +9,8 @@ package manager
 import (
 	"context"
 	"fmt"
+	"math"
+	"reflect"
 	"sort"
 	"strconv"
 	"sync"
@@ -17,9 +19,13 @@ import (
 	policyAPI "github.com/onosproject/onos-a1-dm/go/policy_schemas/traffic_steering_preference/v2"
 	topoAPI "github.com/onosproject/onos-api/go/onos/topo"
 	"github.com/onosproject/onos-lib-go/pkg/logging"
+	appConfig "github.com/onosproject/rimedo-ts/pkg/config"
 	"github.com/onosproject/rimedo-ts/pkg/mho"
 	"github.com/onosproject/rimedo-ts/pkg/northbound/a1"
+	"github.com/onosproject/rimedo-ts/pkg/northbound/state"
+	"github.com/onosproject/rimedo-ts/pkg/policy"
 	"github.com/onosproject/rimedo-ts/pkg/sdran"
+	"github.com/onosproject/rimedo-ts/pkg/steering"
 )
 
 var log = logging.GetLogger("rimedo-ts", "ts-manager")
@@ -60,6 +66,7 @@ func NewManager(sdranConfig sdran.Config, a1Config a1.Config, flag bool) *Manage
 		a1Manager:      *a1Manager,
 		topoIDsEnabled: flag,
 		mutex:          sync.RWMutex{},
+		steeringQueue:  steering.NewQueue(),
 	}
 	return manager
 }
@@ -69,6 +76,7 @@ type Manager struct {
 	a1Manager      a1.Manager
 	topoIDsEnabled bool
 	mutex          sync.RWMutex
+	steeringQueue  *steering.Queue
 }
 
 func (m *Manager) Run() {
@@ -81,6 +89,7 @@ func (m *Manager) Run() {
 
 func (m *Manager) Close() {
 	m.a1Manager.Close(context.Background())
+	m.sdranManager.Close()
 }
 
 func (m *Manager) start() error {
@@ -92,7 +101,52 @@ func (m *Manager) start() error {
 	policyChange := make(chan bool)
 
 	m.sdranManager.AddService(a1.NewA1EIService())
-	m.sdranManager.AddService(a1.NewA1PService(&policyMap, policyChange))
+	m.sdranManager.AddService(a1.NewA1PService(&policyMap, policyChange, m.sdranManager.GetPolicyManager().GetValidatorV2()))
+
+	stateService := state.NewStateService()
+	stateService.AddSection("scoring", func() interface{} {
+		return m.sdranManager.GetPolicyManager().GetScoringV2()
+	})
+	stateService.AddSection("handover", func() interface{} {
+		hysteresis, timeToTrigger := m.sdranManager.GetHandoverTrigger().GetParameters()
+		return map[string]interface{}{
+			"hysteresis":    hysteresis,
+			"timeToTrigger": timeToTrigger.Milliseconds(),
+			"results":       m.sdranManager.GetHandoverStats(),
+		}
+	})
+	stateService.AddSection("control", func() interface{} {
+		return m.sdranManager.GetControlStats()
+	})
+	stateService.AddSection("subscriptions", func() interface{} {
+		return m.sdranManager.GetSubscriptionHealth()
+	})
+	stateService.AddSection("triggers", func() interface{} {
+		return m.sdranManager.GetTriggers()
+	})
+	stateService.AddSection("capabilities", func() interface{} {
+		return m.sdranManager.GetCapabilities()
+	})
+	stateService.AddSection("indications", func() interface{} {
+		return m.sdranManager.GetIndicationStats()
+	})
+	stateService.AddSection("rateLimit", func() interface{} {
+		return m.sdranManager.GetHandoverLimiter().GetStats()
+	})
+	stateService.AddSection("steering", func() interface{} {
+		return map[string]interface{}{
+			"algorithm": m.sdranManager.GetSteeringAlgorithm().Name(),
+			"available": steering.GetAlgorithmNames(),
+		}
+	})
+	stateService.AddSection("policies", func() interface{} {
+		return m.getPoliciesState(ctx)
+	})
+	m.sdranManager.AddService(stateService)
+
+	m.sdranManager.SetUeChangedHandler(func(ueID string) {
+		m.steeringQueue.Add(ueID)
+	})
 
 	handleFlag := false
 
@@ -110,40 +164,29 @@ func (m *Manager) start() error {
 			}
 			log.Debug("")
 			m.checkPolicies(ctx, true, true, true)
+			m.steeringQueue.AddAll()
 		}
 
 	}()
-	flag := true
-	show := false
-	prepare := false
-	counter := 0
-	delay := 3
 	time.Sleep(5 * time.Second)
 	log.Info("\n\n\n\n\n\n\n\n\n\n")
 	handleFlag = true
-	go func() {
-		for {
-			time.Sleep(1 * time.Second)
-			counter++
-			if counter == delay {
-				compareLengths()
-				counter = 0
-				show = true
-			} else if counter == delay-1 {
-				prepare = true
-			} else {
-				show = false
-				prepare = false
-			}
-			m.checkPolicies(ctx, flag, show, prepare)
-			m.showAvailableNodes(ctx, show, prepare)
-			flag = false
-		}
-	}()
+	m.checkPolicies(ctx, true, false, false)
+	go m.runSteering(ctx)
 
 	return nil
 }
 
+// showState logs the policies and the E2 nodes; the lines are measured first so that the headers are drawn as
+// wide as the widest line.
+func (m *Manager) showState(ctx context.Context) {
+	m.checkPolicies(ctx, false, false, true)
+	m.showAvailableNodes(ctx, false, true)
+	compareLengths()
+	m.checkPolicies(ctx, false, true, true)
+	m.showAvailableNodes(ctx, true, true)
+}
+
 func (m *Manager) updatePolicies(ctx context.Context, policyMap map[string][]byte) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -154,10 +197,20 @@ func (m *Manager) updatePolicies(ctx context.Context, policyMap map[string][]byt
 			log.Infof("POLICY MESSAGE: Policy [ID:%v] deleted\n", k)
 		}
 	}
-	for i := range policyMap {
+	policyKeys := make([]string, 0, len(policyMap))
+	for k := range policyMap {
+		policyKeys = append(policyKeys, k)
+	}
+	sort.Strings(policyKeys)
+	var failed error
+	for _, i := range policyKeys {
 		r, err := policyAPI.UnmarshalAPI(policyMap[i])
 		if err == nil {
+			oldPolicy := m.sdranManager.GetPolicy(ctx, i)
 			policyObject := m.sdranManager.CreatePolicy(ctx, i, &r)
+			if oldPolicy == nil || !reflect.DeepEqual(*oldPolicy.API, r) {
+				m.reportConflicts(policyObject)
+			}
 			info := fmt.Sprintf("POLICY MESSAGE: Policy [ID:%v] applied -> ", policyObject.Key)
 			previous := false
 			if policyObject.API.Scope.SliceID != nil {
@@ -209,9 +262,10 @@ func (m *Manager) updatePolicies(ctx context.Context, policyMap map[string][]byt
 			for i := range policyObject.API.TSPResources {
 				info = info + fmt.Sprintf(" - (%v) -", policyObject.API.TSPResources[i].Preference)
 				for j := range policyObject.API.TSPResources[i].CellIDList {
-					nci := *policyObject.API.TSPResources[i].CellIDList[j].CID.NcI
-					plmnId, _ := mho.GetPlmnIdFromMccMnc(policyObject.API.TSPResources[i].CellIDList[j].PlmnID.Mcc, policyObject.API.TSPResources[i].CellIDList[j].PlmnID.Mnc)
-					cgi := m.PlmnIDNciToCGI(plmnId, uint64(nci))
+					cgi, err := m.CellIDToCGI(policyObject.API.TSPResources[i].CellIDList[j])
+					if err != nil {
+						cgi = "INVALID"
+					}
 					info = info + fmt.Sprintf(" CELL [CGI:%v],", cgi)
 				}
 				info = info[0 : len(info)-1]
@@ -220,87 +274,204 @@ func (m *Manager) updatePolicies(ctx context.Context, policyMap map[string][]byt
 			info = info + "\n"
 			log.Info(info)
 		} else {
-			log.Warn("Can't unmarshal the JSON file!")
-			return err
+			log.Warnf("Can't unmarshal the JSON file of Policy [ID:%v], skipping it: %v", i, err)
+			failed = err
 		}
 	}
-	return nil
+	return failed
 }
 
-func (m *Manager) deployPolicies(ctx context.Context) {
+type policyState struct {
+	Enforced          bool     `json:"enforced"`
+	Scope             string   `json:"scope"`
+	Priority          int      `json:"priority"`
+	NotEnforceableUes []string `json:"notEnforceableUes"`
+}
+
+func (m *Manager) getPoliciesState(ctx context.Context) map[string]policyState {
+	output := make(map[string]policyState)
 	policyManager := m.sdranManager.GetPolicyManager()
+	for key, policyObject := range m.sdranManager.GetPolicies(ctx) {
+		policyObject := policyObject
+		output[key] = policyState{
+			Enforced:          policyObject.IsEnforced,
+			Scope:             policy.GetScopeRankV2(&policyObject).String(),
+			Priority:          policyObject.Priority,
+			NotEnforceableUes: policyManager.GetNotEnforceableUesV2(key),
+		}
+	}
+	return output
+}
+
+func (m *Manager) reportConflicts(policyObject *mho.PolicyData) {
+	for _, conflict := range m.sdranManager.GetPolicyManager().GetConflictsV2(policyObject) {
+		log.Warnf("POLICY MESSAGE: Policy [ID:%v] overlaps with Policy [ID:%v] on CELL [NCI:%v, ECI:%v] -> %v (%v, %v scope) takes precedence over %v (%v, %v scope)\n",
+			policyObject.Key, otherKey(conflict, policyObject), int64Value(conflict.CellID.CID.NcI), int64Value(conflict.CellID.CID.EcI),
+			conflict.Winner.Key, conflict.WinnerPref, conflict.WinnerScope, conflict.Loser.Key, conflict.LoserPref, conflict.LoserScope)
+	}
+}
+
+func otherKey(conflict policy.PolicyConflict, policyObject *mho.PolicyData) string {
+	if conflict.Winner.Key == policyObject.Key {
+		return conflict.Loser.Key
+	}
+	return conflict.Winner.Key
+}
+
+func int64Value(value *int64) string {
+	if value == nil {
+		return "-"
+	}
+	return strconv.FormatInt(*value, 10)
+}
+
+// runSteering re-evaluates the UEs queued by measurement reports, RRC state changes and policy changes,
+// the UEs whose time-to-trigger expired and, every sweep interval, all UEs, after logging the state.
+func (m *Manager) runSteering(ctx context.Context) {
+	nextSweep := time.Now()
+	for {
+		now := time.Now()
+		sweepInterval := time.Duration(m.sdranManager.GetConfig().GetSteering().SweepInterval) * time.Millisecond
+		if sweepInterval == 0 {
+			// the sweep is disabled, only wake up to see if it was enabled again
+			nextSweep = now.Add(time.Duration(appConfig.DefaultSweepInterval) * time.Millisecond)
+		} else if !now.Before(nextSweep) {
+			m.showState(ctx)
+			m.steeringQueue.AddAll()
+			nextSweep = now.Add(sweepInterval)
+		} else if nextSweep.After(now.Add(sweepInterval)) {
+			nextSweep = now.Add(sweepInterval)
+		}
+
+		ueIDs, all := m.steeringQueue.Take(now)
+		if all || len(ueIDs) > 0 {
+			m.mutex.Lock()
+			m.deployPolicies(ctx, ueIDs, all)
+			m.mutex.Unlock()
+		}
+
+		wait := time.Until(nextSweep)
+		if deadline, ok := m.steeringQueue.NextDeadline(); ok && time.Until(deadline) < wait {
+			wait = time.Until(deadline)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-m.steeringQueue.Signal():
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+		timer.Stop()
+	}
+}
+
+// deployPolicies evaluates the given UEs, or all of them, and hands them over to the chosen cells.
+func (m *Manager) deployPolicies(ctx context.Context, ueIDs []string, all bool) {
+	policyManager := m.sdranManager.GetPolicyManager()
+	hoTrigger := m.sdranManager.GetHandoverTrigger()
+	algorithm := m.sdranManager.GetSteeringAlgorithm()
 	ues := m.sdranManager.GetUEs(ctx)
+	cells := m.sdranManager.GetCells(ctx)
+	capacities := m.getCellCapacities(ctx, cells)
 	keys := make([]string, 0, len(ues))
-	for k := range ues {
-		keys = append(keys, k)
+	if all {
+		for k := range ues {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+	} else {
+		for _, k := range ueIDs {
+			if _, ok := ues[k]; ok {
+				keys = append(keys, k)
+			}
+		}
 	}
-	sort.Strings(keys)
 
 	for i := range keys {
-		var cellIDs []policyAPI.CellID
-		var rsrps []int
-		fiveQi := ues[keys[i]].FiveQi
-		sd := "456DEF"
+		if handover := ues[keys[i]].Handover; handover != nil {
+			log.Debugf("UE [ID:%v] not evaluated - handover to CELL [CGI:%v] pending", keys[i], handover.TargetCGIString)
+			continue
+		}
+		var candidates []steering.Candidate
+		qosFlows := ues[keys[i]].QosFlows
+		// the scope carries no QoS, the policies are matched against every QoS flow of the UE
 		scopeUe := policyAPI.Scope{
 
-			SliceID: &policyAPI.SliceID{
-				SD:  &sd,
-				Sst: 1,
-				PlmnID: policyAPI.PlmnID{
-					Mcc: "314",
-					Mnc: "628",
-				},
-			},
-			UeID: &keys[i],
-			QosID: &policyAPI.QosID{
-				The5QI: &fiveQi,
-			},
+			SliceID: ues[keys[i]].Slice,
+			UeID:    &keys[i],
+		}
+		if ues[keys[i]].CGI != nil {
+			if servingCellID, err := mho.GetCellIDFromCellGlobalID(ues[keys[i]].CGI); err == nil {
+				scopeUe.CellID = &servingCellID
+			}
 		}
 
 		cgiKeys := make([]string, 0, len(ues[keys[i]].CgiTable))
 		for cgi := range ues[keys[i]].CgiTable {
 			cgiKeys = append(cgiKeys, cgi)
 		}
-		inside := false
+		sort.Strings(cgiKeys)
 		for j := range cgiKeys {
 
-			inside = true
-			cgi := ues[keys[i]].CgiTable[cgiKeys[j]]
-			nci := int64(mho.GetNciFromCellGlobalID(cgi))
-			plmnIdBytes := mho.GetPlmnIDBytesFromCellGlobalID(cgi)
-			plmnId := mho.PlmnIDBytesToInt(plmnIdBytes)
-			mcc, mnc := mho.GetMccMncFromPlmnID(plmnId)
-			cellID := policyAPI.CellID{
-				CID: policyAPI.CID{
-					NcI: &nci,
-				},
-				PlmnID: policyAPI.PlmnID{
-					Mcc: mcc,
-					Mnc: mnc,
-				},
+			cellID, err := mho.GetCellIDFromCellGlobalID(ues[keys[i]].CgiTable[cgiKeys[j]])
+			if err != nil {
+				log.Warnf("CELL [CGI:%v] measured by UE [ID:%v] skipped: %v", cgiKeys[j], keys[i], err)
+				continue
 			}
 
-			cellIDs = append(cellIDs, cellID)
-			rsrps = append(rsrps, int(ues[keys[i]].RsrpTable[cgiKeys[j]]))
+			candidates = append(candidates, steering.Candidate{
+				CellID:    cellID,
+				CGIString: cgiKeys[j],
+				Rsrp:      int(ues[keys[i]].RsrpTable[cgiKeys[j]]),
+				Load:      getCellLoad(cells[cgiKeys[j]], capacities[cgiKeys[j]], keys[i]),
+				Serving:   cgiKeys[j] == ues[keys[i]].CGIString,
+			})
 
 		}
 
-		if inside {
-
-			tsResult := policyManager.GetTsResultForUEV2(scopeUe, rsrps, cellIDs)
-			plmnId, err := mho.GetPlmnIdFromMccMnc(tsResult.PlmnID.Mcc, tsResult.PlmnID.Mnc)
+		if len(candidates) == 0 {
+			continue
+		}
 
-			if err != nil {
-				log.Warnf("Cannot get PLMN ID from these MCC and MNC parameters:%v,%v.", tsResult.PlmnID.Mcc, tsResult.PlmnID.Mnc)
-			} else {
-				targetCellCGI := m.PlmnIDNciToCGI(plmnId, uint64(*tsResult.CID.NcI))
-				m.sdranManager.SwitchUeBetweenCells(ctx, keys[i], targetCellCGI)
+		matching := policyManager.GetMatchingPoliciesForFlowsV2(scopeUe, qosFlows)
+		decision := algorithm.Decide(steering.Input{
+			Ue:         ues[keys[i]],
+			Scope:      scopeUe,
+			QosFlows:   qosFlows,
+			Candidates: candidates,
+			Policies:   matching,
+		})
+		cellIDs := make([]policyAPI.CellID, len(candidates))
+		for j := range candidates {
+			cellIDs[j] = candidates[j].CellID
+		}
+		_, constraining, feasible := policy.GetConstraintsV2(matching, cellIDs)
+		policyManager.SetEnforceableV2(keys[i], constraining, feasible)
+		if decision.Target == nil {
+			log.Debugf("UE [ID:%v] stays in CELL [CGI:%v] - %v", keys[i], ues[keys[i]].CGIString, decision.Reason)
+			continue
+		}
+		servingScore, servingAllowed := decision.Scores[ues[keys[i]].CGIString]
+		servingAllowed = servingAllowed && !math.IsInf(servingScore, -1)
+		targetScore, ok := decision.Scores[decision.Target.CGIString]
+		if !ok {
+			targetScore = math.Inf(1)
+		}
+		if !hoTrigger.Check(keys[i], ues[keys[i]].CGIString, decision.Target.CGIString, servingScore, targetScore, servingAllowed, time.Now()) {
+			if deadline, ok := hoTrigger.Deadline(keys[i]); ok {
+				m.steeringQueue.AddAt(keys[i], deadline)
 			}
-
+			continue
 		}
+		targetCellCGI, err := m.CellIDToCGI(decision.Target.CellID)
 
-		cellIDs = nil
-		rsrps = nil
+		if err != nil {
+			log.Warnf("Cannot get the CGI of the target CELL of UE [ID:%v]: %v", keys[i], err)
+		} else {
+			log.Debugf("UE [ID:%v] steered to CELL [CGI:%v] by %v - %v", keys[i], targetCellCGI, algorithm.Name(), decision.Reason)
+			m.sdranManager.SwitchUeBetweenCells(ctx, keys[i], targetCellCGI)
+		}
 
 	}
 
@@ -377,9 +548,10 @@ func (m *Manager) checkPolicies(ctx context.Context, defaultFlag bool, showFlag
 			for i := range policyObject.API.TSPResources {
 				info = info + fmt.Sprintf(" - (%v) -", policyObject.API.TSPResources[i].Preference)
 				for j := range policyObject.API.TSPResources[i].CellIDList {
-					nci := *policyObject.API.TSPResources[i].CellIDList[j].CID.NcI
-					plmnId, _ := mho.GetPlmnIdFromMccMnc(policyObject.API.TSPResources[i].CellIDList[j].PlmnID.Mcc, policyObject.API.TSPResources[i].CellIDList[j].PlmnID.Mnc)
-					cgi := m.PlmnIDNciToCGI(plmnId, uint64(nci))
+					cgi, err := m.CellIDToCGI(policyObject.API.TSPResources[i].CellIDList[j])
+					if err != nil {
+						cgi = "INVALID"
+					}
 					info = info + fmt.Sprintf(" CELL [CGI:%v],", cgi)
 				}
 				info = info[0 : len(info)-1]
@@ -388,6 +560,9 @@ func (m *Manager) checkPolicies(ctx context.Context, defaultFlag bool, showFlag
 			info = info + "} STATUS: "
 			if policyObject.IsEnforced {
 				info = info + "ENFORCED"
+				if ues := m.sdranManager.GetPolicyManager().GetNotEnforceableUesV2(policyObject.Key); len(ues) > 0 {
+					info = info + fmt.Sprintf(" (NOT ENFORCEABLE FOR UEs %v)", ues)
+				}
 			} else {
 				info = info + "NOT ENFORCED"
 			}
@@ -402,7 +577,35 @@ func (m *Manager) checkPolicies(ctx context.Context, defaultFlag bool, showFlag
 			log.Debug("")
 		}
 	}
-	m.deployPolicies(ctx)
+}
+
+// getCellCapacities returns the configured capacity of every known cell, the cell types are taken from the cache
+// of the E2 cells of the topology only when a capacity is configured per cell type.
+func (m *Manager) getCellCapacities(ctx context.Context, cells map[string]mho.CellData) map[string]int {
+	load := m.sdranManager.GetConfig().GetLoad()
+	cellTypes := make(map[string]string)
+	if len(load.CellTypes) > 0 {
+		for _, cell := range m.sdranManager.GetCellTypes(ctx) {
+			cellTypes[m.CgiFromTopoToIndicationFormat(cell.CGI)] = cell.CellType
+		}
+	}
+	capacities := make(map[string]int, len(cells))
+	for cgi := range cells {
+		capacities[cgi] = load.GetCapacity(cgi, cellTypes[cgi])
+	}
+	return capacities
+}
+
+// getCellLoad returns the ratio of the UEs the cell would serve with the UE attached to its capacity.
+func getCellLoad(cell mho.CellData, capacity int, ueID string) float64 {
+	if capacity <= 0 {
+		return 0
+	}
+	ues := len(cell.Ues)
+	if _, ok := cell.Ues[ueID]; !ok {
+		ues++
+	}
+	return float64(ues) / float64(capacity)
 }
 
 func (m *Manager) showAvailableNodes(ctx context.Context, showFlag bool, prepareFlag bool) {
@@ -475,7 +678,7 @@ func (m *Manager) showAvailableNodes(ctx context.Context, showFlag bool, prepare
 			if ues[key].Idle {
 				status = "IDLE     "
 			}
-			info := fmt.Sprintf("ID:%v STATUS:%v 5QI: %v CGI:%v CGIs(RSRP): [", ueIdString, status, ues[key].FiveQi, cgiString)
+			info := fmt.Sprintf("ID:%v STATUS:%v QoS: %v CGI:%v CGIs(RSRP): [", ueIdString, status, mho.QosFlowsToString(ues[key].QosFlows), cgiString)
 
 			cgi_keys := make([]string, 0, len(ues[key].RsrpTable))
 			for k := range ues[key].RsrpTable {
@@ -538,17 +741,27 @@ func (m *Manager) showAvailableNodes(ctx context.Context, showFlag bool, prepare
 //	}
 //}
 
-func (m *Manager) PlmnIDNciToCGI(plmnID uint64, nci uint64) string {
-	cgi := strconv.FormatInt(int64(plmnID<<36|(nci&0xfffffffff)), 16)
+// CellIDToCGI formats the NCGI or the ECGI of the cell of an A1 policy in the form used for the cells of the UEs.
+func (m *Manager) CellIDToCGI(cellID policyAPI.CellID) (string, error) {
+	cgi, err := mho.NewCGIFromCellID(cellID)
+	if err != nil {
+		return "", err
+	}
 	if m.topoIDsEnabled {
-		cgi = cgi[0:6] + cgi[14:15] + cgi[12:14] + cgi[10:12] + cgi[8:10] + cgi[6:8]
+		return cgi.TopoString(), nil
 	}
-	return cgi
+	return cgi.IndicationString(), nil
 }
 
+// CgiFromTopoToIndicationFormat converts the CGI from the topology form to the one used for the cells of the UEs.
 func (m *Manager) CgiFromTopoToIndicationFormat(cgi string) string {
 	if !m.topoIDsEnabled {
-		cgi = cgi[0:6] + cgi[13:15] + cgi[11:13] + cgi[9:11] + cgi[7:9] + cgi[6:7]
+		cgiObject, err := mho.ParseTopoCGI(cgi)
+		if err != nil {
+			log.Warnf("Cannot convert CGI from the topology form: %v", err)
+			return cgi
+		}
+		return cgiObject.IndicationString()
 	}
 	return cgi
 }
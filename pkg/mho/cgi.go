@@ -0,0 +1,225 @@
+// SPDX-FileCopyrightText: 2019-present Open Networking Foundation <info@opennetworking.org>
+// SPDX-FileCopyrightText: 2019-present Rimedo Labs
+//
+// SPDX-License-Identifier: Apache-2.0
+// Created by RIMEDO-Labs team
+
+package mho
+
+import (
+	"fmt"
+	"strconv"
+
+	policyAPI "github.com/onosproject/onos-a1-dm/go/policy_schemas/traffic_steering_preference/v2"
+	e2sm_v2_ies "github.com/onosproject/onos-e2-sm/servicemodels/e2sm_mho_go/v2/e2sm-v2-ies"
+)
+
+const (
+	nciBits = 36
+	eciBits = 28
+	// ncgiLength and ecgiLength are the lengths of the hex strings of the NCGI and of the ECGI
+	ncgiLength = 6 + nciBits/4
+	ecgiLength = 6 + eciBits/4
+)
+
+// PlmnID is the PLMN identity, MCC and MNC given as decimal digits.
+type PlmnID struct {
+	Mcc string
+	Mnc string
+}
+
+// NewPlmnID validates the MCC (3 digits) and the MNC (2 or 3 digits).
+func NewPlmnID(mcc string, mnc string) (PlmnID, error) {
+	if len(mcc) != 3 || !isDigits(mcc) {
+		return PlmnID{}, fmt.Errorf("invalid MCC %q, 3 digits expected", mcc)
+	}
+	if (len(mnc) != 2 && len(mnc) != 3) || !isDigits(mnc) {
+		return PlmnID{}, fmt.Errorf("invalid MNC %q, 2 or 3 digits expected", mnc)
+	}
+	return PlmnID{Mcc: mcc, Mnc: mnc}, nil
+}
+
+// PlmnIDFromEncoded decodes the 24 bits BCD encoded PLMN ID (MCC2 MCC1, MNC3 MCC3, MNC2 MNC1), where
+// the MNC3 digit is 0xF for a 2 digits MNC.
+func PlmnIDFromEncoded(plmnID uint64) (PlmnID, error) {
+	if plmnID > 0xffffff {
+		return PlmnID{}, fmt.Errorf("invalid PLMN ID %x, more than 24 bits", plmnID)
+	}
+	digit := func(shift uint) byte {
+		return byte(plmnID>>shift) & 0xf
+	}
+	mcc := []byte{digit(16), digit(20), digit(8)}
+	mnc := []byte{digit(0), digit(4)}
+	if mnc3 := digit(12); mnc3 != 0xf {
+		mnc = append(mnc, mnc3)
+	}
+	for _, d := range append(mcc, mnc...) {
+		if d > 9 {
+			return PlmnID{}, fmt.Errorf("invalid PLMN ID %06x, not BCD encoded", plmnID)
+		}
+	}
+	return PlmnID{Mcc: bcdToString(mcc), Mnc: bcdToString(mnc)}, nil
+}
+
+// Encoded returns the 24 bits BCD encoded PLMN ID.
+func (p PlmnID) Encoded() uint64 {
+	digit := func(s string, i int) uint64 {
+		if i >= len(s) {
+			return 0xf
+		}
+		return uint64(s[i] - '0')
+	}
+	return digit(p.Mcc, 1)<<20 | digit(p.Mcc, 0)<<16 | digit(p.Mnc, 2)<<12 | digit(p.Mcc, 2)<<8 | digit(p.Mnc, 1)<<4 | digit(p.Mnc, 0)
+}
+
+func (p PlmnID) String() string {
+	return p.Mcc + "-" + p.Mnc
+}
+
+// CGI is the NCGI of an NR cell or the ECGI of an E-UTRA cell.
+//
+// It is represented as:
+//   - the CGI of the indications: the hex PLMN ID followed by the hex NCI (9 digits) or ECI (7 digits)
+//   - the CGI of the topology: the same, but with the digit pairs of the NCI in the reverse order
+//   - the cell ID of the A1 policies: MCC, MNC and NCI or ECI
+type CGI struct {
+	PlmnID PlmnID
+	// CellIdentity is the 36 bits NCI or the 28 bits ECI
+	CellIdentity uint64
+	Eutra        bool
+}
+
+// NewCGIFromCellGlobalID decodes the CGI of the E2SM-MHO messages.
+func NewCGIFromCellGlobalID(cellGlobalID *e2sm_v2_ies.Cgi) (CGI, error) {
+	plmnIDBytes := GetPlmnIDBytesFromCellGlobalID(cellGlobalID)
+	if len(plmnIDBytes) != 3 {
+		return CGI{}, fmt.Errorf("invalid PLMN ID %x, 3 bytes expected", plmnIDBytes)
+	}
+	plmnID, err := PlmnIDFromEncoded(PlmnIDBytesToInt(plmnIDBytes))
+	if err != nil {
+		return CGI{}, err
+	}
+	if IsEutraCellGlobalID(cellGlobalID) {
+		return CGI{PlmnID: plmnID, CellIdentity: GetEciFromCellGlobalID(cellGlobalID), Eutra: true}, nil
+	}
+	if cellGlobalID.GetNRCgi() == nil {
+		return CGI{}, fmt.Errorf("neither NCGI nor ECGI given")
+	}
+	return CGI{PlmnID: plmnID, CellIdentity: GetNciFromCellGlobalID(cellGlobalID)}, nil
+}
+
+// NewCGIFromCellID converts the cell ID of an A1 policy.
+func NewCGIFromCellID(cellID policyAPI.CellID) (CGI, error) {
+	plmnID, err := NewPlmnID(cellID.PlmnID.Mcc, cellID.PlmnID.Mnc)
+	if err != nil {
+		return CGI{}, err
+	}
+	switch {
+	case cellID.CID.NcI != nil:
+		if *cellID.CID.NcI < 0 || *cellID.CID.NcI >= 1<<nciBits {
+			return CGI{}, fmt.Errorf("invalid NCI %v, %v bits expected", *cellID.CID.NcI, nciBits)
+		}
+		return CGI{PlmnID: plmnID, CellIdentity: uint64(*cellID.CID.NcI)}, nil
+	case cellID.CID.EcI != nil:
+		if *cellID.CID.EcI < 0 || *cellID.CID.EcI >= 1<<eciBits {
+			return CGI{}, fmt.Errorf("invalid ECI %v, %v bits expected", *cellID.CID.EcI, eciBits)
+		}
+		return CGI{PlmnID: plmnID, CellIdentity: uint64(*cellID.CID.EcI), Eutra: true}, nil
+	default:
+		return CGI{}, fmt.Errorf("neither NCI nor ECI given")
+	}
+}
+
+// ParseIndicationCGI parses the CGI in the form of the indications, of 15 hex digits for an NCGI or 13 for an ECGI.
+func ParseIndicationCGI(cgi string) (CGI, error) {
+	var eutra bool
+	switch len(cgi) {
+	case ncgiLength:
+	case ecgiLength:
+		eutra = true
+	default:
+		return CGI{}, fmt.Errorf("invalid CGI %q, %v (NCGI) or %v (ECGI) hex digits expected", cgi, ncgiLength, ecgiLength)
+	}
+	encodedPlmnID, err := strconv.ParseUint(cgi[:6], 16, 32)
+	if err != nil {
+		return CGI{}, fmt.Errorf("invalid CGI %q: %v", cgi, err)
+	}
+	plmnID, err := PlmnIDFromEncoded(encodedPlmnID)
+	if err != nil {
+		return CGI{}, fmt.Errorf("invalid CGI %q: %v", cgi, err)
+	}
+	cellIdentity, err := strconv.ParseUint(cgi[6:], 16, 64)
+	if err != nil {
+		return CGI{}, fmt.Errorf("invalid CGI %q: %v", cgi, err)
+	}
+	return CGI{PlmnID: plmnID, CellIdentity: cellIdentity, Eutra: eutra}, nil
+}
+
+// ParseTopoCGI parses the CGI in the form of the topology.
+func ParseTopoCGI(cgi string) (CGI, error) {
+	if len(cgi) == ncgiLength {
+		cgi = cgi[:6] + nciFromTopo(cgi[6:])
+	}
+	return ParseIndicationCGI(cgi)
+}
+
+// IndicationString formats the CGI in the form of the indications.
+func (c CGI) IndicationString() string {
+	if c.Eutra {
+		return fmt.Sprintf("%06x%07x", c.PlmnID.Encoded(), c.CellIdentity&(1<<eciBits-1))
+	}
+	return fmt.Sprintf("%06x%09x", c.PlmnID.Encoded(), c.CellIdentity&(1<<nciBits-1))
+}
+
+// TopoString formats the CGI in the form of the topology; an ECGI is the same in both forms.
+func (c CGI) TopoString() string {
+	cgi := c.IndicationString()
+	if c.Eutra {
+		return cgi
+	}
+	return cgi[:6] + nciToTopo(cgi[6:])
+}
+
+// CellID converts the CGI to the cell ID of the A1 policies, with either the NCI or the ECI set.
+func (c CGI) CellID() policyAPI.CellID {
+	cellIdentity := int64(c.CellIdentity)
+	cellID := policyAPI.CellID{
+		PlmnID: policyAPI.PlmnID{
+			Mcc: c.PlmnID.Mcc,
+			Mnc: c.PlmnID.Mnc,
+		},
+	}
+	if c.Eutra {
+		cellID.CID.EcI = &cellIdentity
+	} else {
+		cellID.CID.NcI = &cellIdentity
+	}
+	return cellID
+}
+
+// nciToTopo and nciFromTopo convert the 9 hex digits NCI between the forms of the indications and of the topology,
+// which has the digit pairs in the reverse order, the last digit first.
+func nciToTopo(nci string) string {
+	return nci[8:9] + nci[6:8] + nci[4:6] + nci[2:4] + nci[0:2]
+}
+
+func nciFromTopo(nci string) string {
+	return nci[7:9] + nci[5:7] + nci[3:5] + nci[1:3] + nci[0:1]
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func bcdToString(digits []byte) string {
+	s := make([]byte, len(digits))
+	for i, d := range digits {
+		s[i] = '0' + d
+	}
+	return string(s)
+}
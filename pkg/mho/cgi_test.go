@@ -0,0 +1,163 @@
+// SPDX-FileCopyrightText: 2019-present Open Networking Foundation <info@opennetworking.org>
+// SPDX-FileCopyrightText: 2019-present Rimedo Labs
+//
+// SPDX-License-Identifier: Apache-2.0
+// Created by RIMEDO-Labs team
+
+package mho
+
+import (
+	"testing"
+)
+
+func TestPlmnIDFromEncoded(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded uint64
+		plmnID  PlmnID
+		invalid bool
+	}{
+		{name: "3 digits MNC", encoded: 0x138426, plmnID: PlmnID{Mcc: "314", Mnc: "628"}},
+		{name: "2 digits MNC", encoded: 0x13f062, plmnID: PlmnID{Mcc: "310", Mnc: "26"}},
+		{name: "leading zeros", encoded: 0x001100, plmnID: PlmnID{Mcc: "001", Mnc: "001"}},
+		{name: "not BCD", encoded: 0x1a8426, invalid: true},
+		{name: "filler in MCC", encoded: 0x1384f6, invalid: true},
+		{name: "more than 24 bits", encoded: 0x1138426, invalid: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plmnID, err := PlmnIDFromEncoded(test.encoded)
+			if test.invalid {
+				if err == nil {
+					t.Fatalf("PLMN ID %x decoded as %v, error expected", test.encoded, plmnID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if plmnID != test.plmnID {
+				t.Errorf("PLMN ID %x decoded as %v, %v expected", test.encoded, plmnID, test.plmnID)
+			}
+			if encoded := plmnID.Encoded(); encoded != test.encoded {
+				t.Errorf("PLMN ID %v encoded as %06x, %06x expected", plmnID, encoded, test.encoded)
+			}
+		})
+	}
+}
+
+func TestNewPlmnID(t *testing.T) {
+	tests := []struct {
+		mcc     string
+		mnc     string
+		invalid bool
+	}{
+		{mcc: "314", mnc: "628"},
+		{mcc: "310", mnc: "26"},
+		{mcc: "31", mnc: "26", invalid: true},
+		{mcc: "314", mnc: "6", invalid: true},
+		{mcc: "314", mnc: "6280", invalid: true},
+		{mcc: "31a", mnc: "628", invalid: true},
+	}
+	for _, test := range tests {
+		_, err := NewPlmnID(test.mcc, test.mnc)
+		if test.invalid != (err != nil) {
+			t.Errorf("MCC %q and MNC %q: error %v, invalid %v expected", test.mcc, test.mnc, err, test.invalid)
+		}
+	}
+}
+
+// TestGetMccMncFromPlmnID checks the BCD decoding of the PLMN IDs, which used to be read as hex digits: the
+// PLMN ID 138426 is MCC 314 and MNC 628, not MCC 138 and MNC 426.
+func TestGetMccMncFromPlmnID(t *testing.T) {
+	mcc, mnc := GetMccMncFromPlmnID(0x138426)
+	if mcc != "314" || mnc != "628" {
+		t.Errorf("PLMN ID 138426 decoded as MCC %q and MNC %q, MCC 314 and MNC 628 expected", mcc, mnc)
+	}
+	plmnID, err := GetPlmnIdFromMccMnc("314", "628")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plmnID != 0x138426 {
+		t.Errorf("MCC 314 and MNC 628 encoded as %06x, 138426 expected", plmnID)
+	}
+}
+
+func TestParseCGI(t *testing.T) {
+	tests := []struct {
+		name       string
+		indication string
+		topo       string
+		cgi        CGI
+	}{
+		{
+			name:       "NCGI with 3 digits MNC",
+			indication: "13842601c054140",
+			topo:       "13842601454c001",
+			cgi:        CGI{PlmnID: PlmnID{Mcc: "314", Mnc: "628"}, CellIdentity: 470106432},
+		},
+		{
+			name:       "NCGI with 2 digits MNC",
+			indication: "13f062123456789",
+			topo:       "13f062978563412",
+			cgi:        CGI{PlmnID: PlmnID{Mcc: "310", Mnc: "26"}, CellIdentity: 0x123456789},
+		},
+		{
+			name:       "ECGI with 3 digits MNC",
+			indication: "0011001234567",
+			topo:       "0011001234567",
+			cgi:        CGI{PlmnID: PlmnID{Mcc: "001", Mnc: "001"}, CellIdentity: 0x1234567, Eutra: true},
+		},
+		{
+			name:       "ECGI with 2 digits MNC",
+			indication: "02f810abcdef0",
+			topo:       "02f810abcdef0",
+			cgi:        CGI{PlmnID: PlmnID{Mcc: "208", Mnc: "01"}, CellIdentity: 0xabcdef0, Eutra: true},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cgi, err := ParseIndicationCGI(test.indication)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cgi != test.cgi {
+				t.Errorf("indication CGI %v parsed as %+v, %+v expected", test.indication, cgi, test.cgi)
+			}
+			cgi, err = ParseTopoCGI(test.topo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cgi != test.cgi {
+				t.Errorf("topology CGI %v parsed as %+v, %+v expected", test.topo, cgi, test.cgi)
+			}
+			if s := test.cgi.IndicationString(); s != test.indication {
+				t.Errorf("CGI %+v formatted as indication CGI %v, %v expected", test.cgi, s, test.indication)
+			}
+			if s := test.cgi.TopoString(); s != test.topo {
+				t.Errorf("CGI %+v formatted as topology CGI %v, %v expected", test.cgi, s, test.topo)
+			}
+			cgi, err = NewCGIFromCellID(test.cgi.CellID())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cgi != test.cgi {
+				t.Errorf("cell ID of CGI %+v converted back as %+v", test.cgi, cgi)
+			}
+		})
+	}
+}
+
+func TestParseInvalidCGI(t *testing.T) {
+	for _, cgi := range []string{
+		"",
+		"13842601c05414",
+		"13842601c0541400",
+		"1a842601c054140",
+		"13842601c05414g",
+	} {
+		if _, err := ParseIndicationCGI(cgi); err == nil {
+			t.Errorf("invalid CGI %q parsed", cgi)
+		}
+	}
+}
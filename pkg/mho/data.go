@@ -6,27 +6,64 @@
 package mho
 
 import (
+	"time"
+
 	policyAPI "github.com/onosproject/onos-a1-dm/go/policy_schemas/traffic_steering_preference/v2"
 	e2sm_v2_ies "github.com/onosproject/onos-e2-sm/servicemodels/e2sm_mho_go/v2/e2sm-v2-ies"
 )
 
 type UeData struct {
-	UeID          string
-	E2NodeID      string
-	CGI           *e2sm_v2_ies.Cgi
-	CGIString     string
-	RrcState      string
-	FiveQi        int64
+	UeID     string
+	E2NodeID string
+	// UeIdentity is the UE ID as received in the last indication, it is sent back as is in the control messages
+	UeIdentity *e2sm_v2_ies.Ueid
+	Slice      *policyAPI.SliceID
+	CGI        *e2sm_v2_ies.Cgi
+	CGIString  string
+	RrcState   string
+	// FiveQi is the 5QI of the first QoS flow of the UE, -1 if it has none
+	FiveQi int64
+	// QosFlows are the 5QIs (NR) and QCIs (E-UTRA) of the QoS flows of the UE reported for its serving cell
+	QosFlows      []policyAPI.QosID
 	RsrpServing   int32
 	RsrpNeighbors map[string]int32
 	RsrpTable     map[string]int32
 	CgiTable      map[string]*e2sm_v2_ies.Cgi
 	Idle          bool
+	Handover      *PendingHandover
+	LastHandover  *HandoverResult
+}
+
+// PendingHandover is a handover requested by the xApp and not yet confirmed by an indication from the target cell.
+type PendingHandover struct {
+	SourceCGIString string
+	SourceCGI       *e2sm_v2_ies.Cgi
+	TargetCGIString string
+	Started         time.Time
+	// Acknowledged is set when the E2 node acknowledged the control request
+	Acknowledged bool
+}
+
+// HandoverResult is the outcome of the last handover requested by the xApp for the UE.
+type HandoverResult struct {
+	TargetCGIString string
+	Success         bool
+	Reason          string
+	Finished        time.Time
+}
+
+// HandoverStats counts the handovers requested by the xApp by their outcome.
+type HandoverStats struct {
+	Pending   int    `json:"pending"`
+	Succeeded uint64 `json:"succeeded"`
+	Failed    uint64 `json:"failed"`
 }
 
 type CellData struct {
-	CGI                    *e2sm_v2_ies.Cgi
-	CGIString              string
+	CGI       *e2sm_v2_ies.Cgi
+	CGIString string
+	// E2NodeID is the E2 node serving the cell, known once a UE served by the cell was reported
+	E2NodeID               string
 	CumulativeHandoversIn  int
 	CumulativeHandoversOut int
 	Ues                    map[string]*UeData
@@ -36,4 +73,6 @@ type PolicyData struct {
 	Key        string
 	API        *policyAPI.API
 	IsEnforced bool
+	Priority   int
+	CreatedAt  time.Time
 }
@@ -0,0 +1,214 @@
+// SPDX-FileCopyrightText: 2019-present Open Networking Foundation <info@opennetworking.org>
+// SPDX-FileCopyrightText: 2019-present Rimedo Labs
+//
+// SPDX-License-Identifier: Apache-2.0
+// Created by RIMEDO-Labs team
+
+package mho
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Overflow policies of the worker queues, applied to an indication for a full queue
+const (
+	// OverflowBlock waits for the worker, the indications then pile up in the input queue and slow down the E2
+	// indication streams
+	OverflowBlock = "block"
+	// OverflowDropNewest drops the indication
+	OverflowDropNewest = "drop-newest"
+	// OverflowDropOldest drops the oldest indication of the queue to make room for the indication
+	OverflowDropOldest = "drop-oldest"
+)
+
+const (
+	DefaultIndicationWorkers   = 8
+	DefaultIndicationQueueSize = 128
+)
+
+// QueueStats shows how full a queue of indications is.
+type QueueStats struct {
+	Length   int `json:"length"`
+	Capacity int `json:"capacity"`
+	// MaxLength is the longest the queue has been
+	MaxLength int `json:"maxLength"`
+}
+
+// IndicationStats counts the indications by their outcome, the dropped and blocked ones showing the backpressure.
+type IndicationStats struct {
+	Overflow     string       `json:"overflow"`
+	InputQueue   QueueStats   `json:"inputQueue"`
+	WorkerQueues []QueueStats `json:"workerQueues"`
+	Received     uint64       `json:"received"`
+	Handled      uint64       `json:"handled"`
+	// Invalid counts the indications that couldn't be decoded, those without a UE ID are dropped before the workers
+	Invalid uint64 `json:"invalid"`
+	// Dropped counts the indications dropped for a full worker queue
+	Dropped uint64 `json:"dropped"`
+	// Blocked counts the indications that had to wait for a full worker queue
+	Blocked uint64 `json:"blocked"`
+}
+
+// dispatcher hands the indications over to the workers, sharded by UE ID so that the indications of a UE are
+// handled one at a time in the order they were received.
+type dispatcher struct {
+	queues   []chan func()
+	overflow string
+	stats    IndicationStats
+	mu       sync.Mutex
+}
+
+func newDispatcher(workers int, queueSize int) *dispatcher {
+	if workers <= 0 {
+		workers = DefaultIndicationWorkers
+	}
+	if queueSize <= 0 {
+		queueSize = DefaultIndicationQueueSize
+	}
+	d := &dispatcher{
+		queues:   make([]chan func(), workers),
+		overflow: OverflowBlock,
+		stats: IndicationStats{
+			WorkerQueues: make([]QueueStats, workers),
+		},
+	}
+	for i := range d.queues {
+		d.queues[i] = make(chan func(), queueSize)
+		d.stats.WorkerQueues[i].Capacity = queueSize
+	}
+	return d
+}
+
+func (d *dispatcher) start() {
+	for _, queue := range d.queues {
+		go func(queue chan func()) {
+			for handle := range queue {
+				handle()
+				d.mu.Lock()
+				d.stats.Handled++
+				d.mu.Unlock()
+			}
+		}(queue)
+	}
+}
+
+// stop lets the workers finish the queued indications and exit
+func (d *dispatcher) stop() {
+	for _, queue := range d.queues {
+		close(queue)
+	}
+}
+
+func (d *dispatcher) setOverflow(overflow string) error {
+	switch overflow {
+	case OverflowBlock, OverflowDropNewest, OverflowDropOldest:
+	default:
+		return fmt.Errorf("unknown overflow policy %v", overflow)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.overflow = overflow
+	return nil
+}
+
+// received counts an indication taken from the input queue, with the given number of indications left in it
+func (d *dispatcher) received(inputLength int, valid bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stats.Received++
+	if !valid {
+		d.stats.Invalid++
+	}
+	if inputLength+1 > d.stats.InputQueue.MaxLength {
+		d.stats.InputQueue.MaxLength = inputLength + 1
+	}
+}
+
+// dispatch queues the handling of an indication of the UE to its worker, applying the overflow policy if the queue
+// is full. It only returns early without queueing if the context is done while blocked.
+func (d *dispatcher) dispatch(ctx context.Context, ueID int64, handle func()) {
+	shard := int(uint64(ueID) % uint64(len(d.queues)))
+	queue := d.queues[shard]
+
+	d.mu.Lock()
+	overflow := d.overflow
+	d.mu.Unlock()
+
+	select {
+	case queue <- handle:
+	default:
+		switch overflow {
+		case OverflowDropNewest:
+			d.drop()
+			return
+		case OverflowDropOldest:
+			select {
+			case <-queue:
+				d.drop()
+			default:
+			}
+			// there is room now unless the queue was refilled, then wait like the blocking policy
+			select {
+			case queue <- handle:
+			case <-ctx.Done():
+				return
+			}
+		default:
+			d.mu.Lock()
+			d.stats.Blocked++
+			d.mu.Unlock()
+			select {
+			case queue <- handle:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if length := len(queue); length > d.stats.WorkerQueues[shard].MaxLength {
+		d.stats.WorkerQueues[shard].MaxLength = length
+	}
+}
+
+// invalid counts an indication that a worker couldn't decode
+func (d *dispatcher) invalid() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stats.Invalid++
+}
+
+func (d *dispatcher) drop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stats.Dropped++
+}
+
+func (d *dispatcher) getStats() IndicationStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	stats := d.stats
+	stats.Overflow = d.overflow
+	stats.WorkerQueues = make([]QueueStats, len(d.queues))
+	for i, queue := range d.queues {
+		stats.WorkerQueues[i] = d.stats.WorkerQueues[i]
+		stats.WorkerQueues[i].Length = len(queue)
+	}
+	return stats
+}
+
+// SetIndicationOverflow sets the policy applied to an indication for a full worker queue.
+func (c *Controller) SetIndicationOverflow(overflow string) error {
+	return c.dispatcher.setOverflow(overflow)
+}
+
+// GetIndicationStats returns the counters and the queue lengths of the indication processing.
+func (c *Controller) GetIndicationStats() IndicationStats {
+	stats := c.dispatcher.getStats()
+	stats.InputQueue.Length = len(c.IndChan)
+	stats.InputQueue.Capacity = cap(c.IndChan)
+	return stats
+}
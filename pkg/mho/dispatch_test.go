@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: 2019-present Open Networking Foundation <info@opennetworking.org>
+// SPDX-FileCopyrightText: 2019-present Rimedo Labs
+//
+// SPDX-License-Identifier: Apache-2.0
+// Created by RIMEDO-Labs team
+
+package mho
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fill dispatches the numbered indications of the UE to the dispatcher, whose workers are not started, and
+// returns the numbers left in the queue of the UE
+func fill(ctx context.Context, d *dispatcher, ueID int64, count int) []int {
+	handled := make([]int, 0)
+	for i := 1; i <= count; i++ {
+		i := i
+		d.dispatch(ctx, ueID, func() {
+			handled = append(handled, i)
+		})
+	}
+	queue := d.queues[int(uint64(ueID)%uint64(len(d.queues)))]
+	for len(queue) > 0 {
+		(<-queue)()
+	}
+	return handled
+}
+
+func TestDispatchDropNewest(t *testing.T) {
+	d := newDispatcher(1, 2)
+	if err := d.setOverflow(OverflowDropNewest); err != nil {
+		t.Fatal(err)
+	}
+	if queued := fill(context.Background(), d, 1, 4); !reflect.DeepEqual(queued, []int{1, 2}) {
+		t.Errorf("indications %v queued, the first two expected", queued)
+	}
+	if stats := d.getStats(); stats.Dropped != 2 || stats.Blocked != 0 {
+		t.Errorf("%v dropped and %v blocked indications counted, 2 dropped expected", stats.Dropped, stats.Blocked)
+	}
+}
+
+func TestDispatchDropOldest(t *testing.T) {
+	d := newDispatcher(1, 2)
+	if err := d.setOverflow(OverflowDropOldest); err != nil {
+		t.Fatal(err)
+	}
+	if queued := fill(context.Background(), d, 1, 4); !reflect.DeepEqual(queued, []int{3, 4}) {
+		t.Errorf("indications %v queued, the last two expected", queued)
+	}
+	if stats := d.getStats(); stats.Dropped != 2 || stats.Blocked != 0 {
+		t.Errorf("%v dropped and %v blocked indications counted, 2 dropped expected", stats.Dropped, stats.Blocked)
+	}
+}
+
+func TestDispatchBlock(t *testing.T) {
+	d := newDispatcher(1, 2)
+
+	// a done context lets the blocked dispatch return without queueing
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if queued := fill(ctx, d, 1, 3); !reflect.DeepEqual(queued, []int{1, 2}) {
+		t.Errorf("indications %v queued, the first two expected", queued)
+	}
+	if stats := d.getStats(); stats.Dropped != 0 || stats.Blocked != 1 {
+		t.Errorf("%v dropped and %v blocked indications counted, 1 blocked expected", stats.Dropped, stats.Blocked)
+	}
+
+	// otherwise it waits for the worker
+	d.start()
+	defer d.stop()
+	done := make(chan int, 10)
+	for i := 1; i <= 10; i++ {
+		i := i
+		d.dispatch(context.Background(), 1, func() {
+			time.Sleep(time.Millisecond)
+			done <- i
+		})
+	}
+	for i := 1; i <= 10; i++ {
+		if handled := <-done; handled != i {
+			t.Fatalf("indication %v handled, %v expected", handled, i)
+		}
+	}
+	if stats := d.getStats(); stats.Dropped != 0 {
+		t.Errorf("%v indications dropped by the blocking policy", stats.Dropped)
+	}
+}
+
+func TestDispatchShards(t *testing.T) {
+	d := newDispatcher(2, 1)
+	if err := d.setOverflow(OverflowDropNewest); err != nil {
+		t.Fatal(err)
+	}
+	// UEs 1 and 3 share a worker, UE 2 has the other one
+	for _, ueID := range []int64{1, 2, 3} {
+		d.dispatch(context.Background(), ueID, func() {})
+	}
+	stats := d.getStats()
+	if stats.Dropped != 1 {
+		t.Errorf("%v indications dropped, 1 expected", stats.Dropped)
+	}
+	if stats.WorkerQueues[0].Length != 1 || stats.WorkerQueues[1].Length != 1 {
+		t.Errorf("worker queues %+v, one indication in each expected", stats.WorkerQueues)
+	}
+}
+
+func TestDispatchDefaults(t *testing.T) {
+	d := newDispatcher(0, 0)
+	if len(d.queues) != DefaultIndicationWorkers || cap(d.queues[0]) != DefaultIndicationQueueSize {
+		t.Errorf("%v workers queueing %v indications, the defaults expected", len(d.queues), cap(d.queues[0]))
+	}
+	if err := d.setOverflow("drop-all"); err == nil {
+		t.Error("unknown overflow policy accepted")
+	}
+	if stats := d.getStats(); stats.Overflow != OverflowBlock {
+		t.Errorf("overflow policy %v, %v expected", stats.Overflow, OverflowBlock)
+	}
+}
@@ -7,10 +7,14 @@ package mho
 
 import (
 	"context"
+	"fmt"
 	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	"hash/fnv"
 	"reflect"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	policyAPI "github.com/onosproject/onos-a1-dm/go/policy_schemas/traffic_steering_preference/v2"
 	e2sm_mho "github.com/onosproject/onos-e2-sm/servicemodels/e2sm_mho_go/v2/e2sm-mho-go"
@@ -26,9 +30,14 @@ type E2NodeIndication struct {
 	NodeID      string
 	TriggerType e2sm_mho.MhoTriggerType
 	IndMsg      e2api.Indication
+	// Timestamp is when the indication was received
+	Timestamp time.Time
 }
 
-func NewController(indChan chan *E2NodeIndication, ueStore store.Store, cellStore store.Store, onosPolicyStore store.Store, policies map[string]*PolicyData, flag bool) *Controller {
+// NewController creates the controller of the indications read from indChan, handled by the given number of workers
+// queueing up to indicationQueueSize indications each; 0 is replaced by the default.
+func NewController(indChan chan *E2NodeIndication, ueStore store.Store, cellStore store.Store, onosPolicyStore store.Store, policies map[string]*PolicyData, flag bool,
+	indicationWorkers int, indicationQueueSize int) *Controller {
 
 	return &Controller{
 		IndChan:         indChan,
@@ -39,174 +48,535 @@ func NewController(indChan chan *E2NodeIndication, ueStore store.Store, cellStor
 		cells:           make(map[string]*CellData),
 		policies:        policies,
 		topoIDsEnabled:  flag,
+		handoverTimeout: DefaultHandoverTimeout,
+		dispatcher:      newDispatcher(indicationWorkers, indicationQueueSize),
+		ctx:             context.Background(),
 	}
 }
 
+// DefaultHandoverTimeout is how long a handover waits for the confirmation if not configured.
+const DefaultHandoverTimeout = 5 * time.Second
+
+// ueLockCount is how many locks the UEs share, see lockUe
+const ueLockCount = 64
+
 type Controller struct {
 	IndChan         chan *E2NodeIndication
 	ueStore         store.Store
 	cellStore       store.Store
 	onosPolicyStore store.Store
 	mu              sync.RWMutex
+	ueLocks         [ueLockCount]sync.Mutex
 	cells           map[string]*CellData
 	policies        map[string]*PolicyData
 	topoIDsEnabled  bool
+	ueChanged       func(ueID string)
+	ueDetached      func(ueID string)
+	sliceResolver   func(ueData *UeData) *policyAPI.SliceID
+	handoverTimeout time.Duration
+	handoverStats   HandoverStats
+	dispatcher      *dispatcher
+	recorder        func(indication *E2NodeIndication)
+	recorderMu      sync.RWMutex
+	// ctx is the context of Run, used by the work outliving the calls into the controller
+	ctx context.Context
+}
+
+// SetUeChangedHandler sets the function called after a measurement report or an RRC state change updated
+// the UE. It is called with the UE locked, so it must not block or call back into the controller.
+func (c *Controller) SetUeChangedHandler(handler func(ueID string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ueChanged = handler
+}
+
+// SetUeDetachedHandler sets the function called when a UE went idle and left its cell. It is called with the UE
+// locked and the controller lock held, so it must not block or call back into the controller either.
+func (c *Controller) SetUeDetachedHandler(handler func(ueID string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ueDetached = handler
+}
+
+// SetSliceResolver sets the function returning the slice of the UE, called for every indication.
+func (c *Controller) SetSliceResolver(resolver func(ueData *UeData) *policyAPI.SliceID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sliceResolver = resolver
+}
+
+// SetIndicationRecorder sets the function called with every indication taken from IndChan, in the order they are
+// taken, before it is decoded; nil stops the recording. It is called by the single reader of IndChan, so a slow
+// recorder slows down the processing of the indications.
+func (c *Controller) SetIndicationRecorder(recorder func(indication *E2NodeIndication)) {
+	c.recorderMu.Lock()
+	defer c.recorderMu.Unlock()
+	c.recorder = recorder
+}
+
+func (c *Controller) record(indication *E2NodeIndication) {
+	c.recorderMu.RLock()
+	defer c.recorderMu.RUnlock()
+	if c.recorder != nil {
+		c.recorder(indication)
+	}
+}
+
+func (c *Controller) getSlice(ueData *UeData) *policyAPI.SliceID {
+	if c.sliceResolver == nil {
+		return nil
+	}
+	return c.sliceResolver(ueData)
+}
+
+func (c *Controller) notifyUeChanged(ueID string) {
+	c.mu.RLock()
+	ueChanged := c.ueChanged
+	c.mu.RUnlock()
+	if ueChanged != nil {
+		ueChanged(ueID)
+	}
+}
+
+func (c *Controller) notifyUeDetached(ueID string) {
+	if c.ueDetached != nil {
+		c.ueDetached(ueID)
+	}
 }
 
 func (c *Controller) Run(ctx context.Context, flag *bool) {
+	c.mu.Lock()
+	c.ctx = ctx
+	c.mu.Unlock()
 	go c.listenIndChan(ctx, flag)
 }
 
+// listenIndChan dispatches the handling of the indications to the workers by their UE, until IndChan is closed.
+// Only the UE ID is decoded here, the rest of the indication is decoded by the worker.
 func (c *Controller) listenIndChan(ctx context.Context, flag *bool) {
-	var err error
+	c.dispatcher.start()
+	defer c.dispatcher.stop()
 	for indMsg := range c.IndChan {
-
-		indHeaderByte := indMsg.IndMsg.Header
-		indMessageByte := indMsg.IndMsg.Payload
-		e2NodeID := indMsg.NodeID
-
-		indHeader := e2sm_mho.E2SmMhoIndicationHeader{}
-		if err = proto.Unmarshal(indHeaderByte, &indHeader); err == nil {
-			indMessage := e2sm_mho.E2SmMhoIndicationMessage{}
-			if err = proto.Unmarshal(indMessageByte, &indMessage); err == nil {
-				switch x := indMessage.E2SmMhoIndicationMessage.(type) {
-				case *e2sm_mho.E2SmMhoIndicationMessage_IndicationMessageFormat1:
-					if indMsg.TriggerType == e2sm_mho.MhoTriggerType_MHO_TRIGGER_TYPE_UPON_RCV_MEAS_REPORT {
-						go c.handleMeasReport(ctx, indHeader.GetIndicationHeaderFormat1(), indMessage.GetIndicationMessageFormat1(), e2NodeID, flag)
-					} else if indMsg.TriggerType == e2sm_mho.MhoTriggerType_MHO_TRIGGER_TYPE_PERIODIC {
-						go c.handlePeriodicReport(ctx, indHeader.GetIndicationHeaderFormat1(), indMessage.GetIndicationMessageFormat1(), e2NodeID, flag)
-					}
-				case *e2sm_mho.E2SmMhoIndicationMessage_IndicationMessageFormat2:
-					go c.handleRrcState(ctx, indHeader.GetIndicationHeaderFormat1(), indMessage.GetIndicationMessageFormat2(), e2NodeID)
-				default:
-					log.Warnf("Unknown MHO indication message format, indication message: %v", x)
-				}
-			}
-		}
+		c.record(indMsg)
+		ueID, err := GetUeIDFromIndicationMessage(indMsg.IndMsg.Payload)
+		c.dispatcher.received(len(c.IndChan), err == nil)
 		if err != nil {
-			log.Error(err)
+			log.Errorf("Indication from E2 node %v dropped: %v", indMsg.NodeID, err)
+			continue
+		}
+		indication := indMsg
+		c.dispatcher.dispatch(ctx, ueID, func() {
+			if err := c.handleIndication(ctx, indication, flag); err != nil {
+				log.Errorf("Indication from E2 node %v dropped: %v", indication.NodeID, err)
+				c.dispatcher.invalid()
+			}
+		})
+	}
+}
+
+// handleIndication decodes the indication and handles it by its trigger type and format
+func (c *Controller) handleIndication(ctx context.Context, indMsg *E2NodeIndication, flag *bool) error {
+	e2NodeID := indMsg.NodeID
+
+	indHeader := e2sm_mho.E2SmMhoIndicationHeader{}
+	if err := proto.Unmarshal(indMsg.IndMsg.Header, &indHeader); err != nil {
+		return err
+	}
+	indMessage := e2sm_mho.E2SmMhoIndicationMessage{}
+	if err := proto.Unmarshal(indMsg.IndMsg.Payload, &indMessage); err != nil {
+		return err
+	}
+	header := indHeader.GetIndicationHeaderFormat1()
+
+	switch x := indMessage.E2SmMhoIndicationMessage.(type) {
+	case *e2sm_mho.E2SmMhoIndicationMessage_IndicationMessageFormat1:
+		message := indMessage.GetIndicationMessageFormat1()
+		switch indMsg.TriggerType {
+		case e2sm_mho.MhoTriggerType_MHO_TRIGGER_TYPE_UPON_RCV_MEAS_REPORT:
+			c.handleMeasReport(ctx, header, message, e2NodeID, flag)
+		case e2sm_mho.MhoTriggerType_MHO_TRIGGER_TYPE_PERIODIC:
+			c.handlePeriodicReport(ctx, header, message, e2NodeID, flag)
 		}
+	case *e2sm_mho.E2SmMhoIndicationMessage_IndicationMessageFormat2:
+		message := indMessage.GetIndicationMessageFormat2()
+		c.handleRrcState(ctx, header, message, e2NodeID)
+	default:
+		log.Warnf("Unknown MHO indication message format, indication message: %v", x)
 	}
+	return nil
 }
 
+// The handlers of the indications decode the measurements without any lock, then lock the UE for the update so
+// that a handover doesn't interleave with it. The controller lock is only held while the UE is looked up and
+// attached and while the cells are updated, see getIndicationUe.
+
 func (c *Controller) handlePeriodicReport(ctx context.Context, header *e2sm_mho.E2SmMhoIndicationHeaderFormat1, message *e2sm_mho.E2SmMhoIndicationMessageFormat1, e2NodeID string, flag *bool) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
 	ueID, err := GetUeID(message.GetUeId())
 	if err != nil {
 		log.Errorf("handlePeriodicReport() couldn't extract UeID: %v", err)
+		return
 	}
 	cgi := GetCGIFromIndicationHeader(header)
 	cgi = c.ConvertCgiToTheRightForm(cgi)
 	cgiObject := header.GetCgi()
+	ueIdString := getUeIDString(ueID)
 
-	ueIdString := strconv.Itoa(int(ueID))
-	n := (16 - len(ueIdString))
-	for i := 0; i < n; i++ {
-		ueIdString = "0" + ueIdString
-	}
-	var ueData *UeData
-	newUe := false
-	ueData = c.GetUe(ctx, ueIdString)
+	rsrpServing, rsrpNeighbors, rsrpTable, cgiTable := c.GetRsrpFromMeasReport(ctx, GetCGIFromIndicationHeader(header), message.MeasReport)
+	qosFlows := c.GetQosFlowsFromMeasReport(ctx, GetCGIFromIndicationHeader(header), message.MeasReport)
+
+	unlock := c.lockUe(ueIdString)
+	defer unlock()
+	ueData, newUe := c.getIndicationUe(ctx, ueIdString, message.GetUeId(), cgi, cgiObject, e2NodeID, cgiTable)
 	if ueData == nil {
-		ueData = c.CreateUe(ctx, ueIdString)
-		c.AttachUe(ctx, ueData, cgi, cgiObject)
-		newUe = true
-	} else if ueData.CGIString != cgi {
 		return
 	}
 
-	ueData.E2NodeID = e2NodeID
-
-	rsrpServing, rsrpNeighbors, rsrpTable, cgiTable := c.GetRsrpFromMeasReport(ctx, GetNciFromCellGlobalID(header.GetCgi()), message.MeasReport)
-
-	old5qi := ueData.FiveQi
-	ueData.FiveQi = c.GetFiveQiFromMeasReport(ctx, GetNciFromCellGlobalID(header.GetCgi()), message.MeasReport)
+	qosChanged := !reflect.DeepEqual(ueData.QosFlows, qosFlows)
+	ueData.QosFlows = qosFlows
+	ueData.FiveQi = GetFiveQiFromQosFlows(ueData.QosFlows)
 
-	if *flag && (old5qi != ueData.FiveQi) {
-		log.Infof("\t\tQUALITY MESSAGE: 5QI for UE [ID:%v] changed [5QI:%v]\n", ueData.UeID, ueData.FiveQi)
+	if *flag && qosChanged {
+		log.Infof("\t\tQUALITY MESSAGE: QoS flows for UE [ID:%v] changed [%v]\n", ueData.UeID, QosFlowsToString(ueData.QosFlows))
 	}
 
-	if !newUe && rsrpServing == ueData.RsrpServing && reflect.DeepEqual(rsrpNeighbors, ueData.RsrpNeighbors) {
+	if !newUe && !qosChanged && rsrpServing == ueData.RsrpServing && reflect.DeepEqual(rsrpNeighbors, ueData.RsrpNeighbors) {
 		return
 	}
 
 	ueData.RsrpServing, ueData.RsrpNeighbors, ueData.RsrpTable, ueData.CgiTable = rsrpServing, rsrpNeighbors, rsrpTable, cgiTable
 	c.SetUe(ctx, ueData)
+	c.notifyUeChanged(ueData.UeID)
 
 }
 
 func (c *Controller) handleMeasReport(ctx context.Context, header *e2sm_mho.E2SmMhoIndicationHeaderFormat1, message *e2sm_mho.E2SmMhoIndicationMessageFormat1, e2NodeID string, flag *bool) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
 	ueID, err := GetUeID(message.GetUeId())
 	if err != nil {
 		log.Errorf("handleMeasReport() couldn't extract UeID: %v", err)
+		return
 	}
 	cgi := GetCGIFromIndicationHeader(header)
 	cgi = c.ConvertCgiToTheRightForm(cgi)
 	cgiObject := header.GetCgi()
+	ueIdString := getUeIDString(ueID)
 
-	ueIdString := strconv.Itoa(int(ueID))
-	n := (16 - len(ueIdString))
-	for i := 0; i < n; i++ {
-		ueIdString = "0" + ueIdString
-	}
-	var ueData *UeData
-	ueData = c.GetUe(ctx, ueIdString)
+	rsrpServing, rsrpNeighbors, rsrpTable, cgiTable := c.GetRsrpFromMeasReport(ctx, GetCGIFromIndicationHeader(header), message.MeasReport)
+	qosFlows := c.GetQosFlowsFromMeasReport(ctx, GetCGIFromIndicationHeader(header), message.MeasReport)
+
+	unlock := c.lockUe(ueIdString)
+	defer unlock()
+	ueData, _ := c.getIndicationUe(ctx, ueIdString, message.GetUeId(), cgi, cgiObject, e2NodeID, cgiTable)
 	if ueData == nil {
-		ueData = c.CreateUe(ctx, ueIdString)
-		c.AttachUe(ctx, ueData, cgi, cgiObject)
-	} else if ueData.CGIString != cgi {
 		return
 	}
 
-	ueData.E2NodeID = e2NodeID
+	ueData.RsrpServing, ueData.RsrpNeighbors, ueData.RsrpTable, ueData.CgiTable = rsrpServing, rsrpNeighbors, rsrpTable, cgiTable
 
-	ueData.RsrpServing, ueData.RsrpNeighbors, ueData.RsrpTable, ueData.CgiTable = c.GetRsrpFromMeasReport(ctx, GetNciFromCellGlobalID(header.GetCgi()), message.MeasReport)
+	qosChanged := !reflect.DeepEqual(ueData.QosFlows, qosFlows)
+	ueData.QosFlows = qosFlows
+	ueData.FiveQi = GetFiveQiFromQosFlows(ueData.QosFlows)
 
-	old5qi := ueData.FiveQi
-	ueData.FiveQi = c.GetFiveQiFromMeasReport(ctx, GetNciFromCellGlobalID(header.GetCgi()), message.MeasReport)
-	if *flag && (old5qi != ueData.FiveQi) {
-		log.Infof("\t\tQUALITY MESSAGE: 5QI for UE [ID:%v] changed [5QI:%v]\n", ueData.UeID, ueData.FiveQi)
+	if *flag && qosChanged {
+		log.Infof("\t\tQUALITY MESSAGE: QoS flows for UE [ID:%v] changed [%v]\n", ueData.UeID, QosFlowsToString(ueData.QosFlows))
 	}
 
 	c.SetUe(ctx, ueData)
+	c.notifyUeChanged(ueData.UeID)
 
 }
 
 func (c *Controller) handleRrcState(ctx context.Context, header *e2sm_mho.E2SmMhoIndicationHeaderFormat1, message *e2sm_mho.E2SmMhoIndicationMessageFormat2, e2NodeID string) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
 	ueID, err := GetUeID(message.GetUeId())
 	if err != nil {
 		log.Errorf("handleRrcState() couldn't extract UeID: %v", err)
+		return
 	}
 	cgi := GetCGIFromIndicationHeader(header)
 	cgi = c.ConvertCgiToTheRightForm(cgi)
 	cgiObject := header.GetCgi()
+	ueIdString := getUeIDString(ueID)
+
+	unlock := c.lockUe(ueIdString)
+	defer unlock()
+	ueData, _ := c.getIndicationUe(ctx, ueIdString, message.GetUeId(), cgi, cgiObject, e2NodeID, nil)
+	if ueData == nil {
+		return
+	}
+
+	newRrcState := message.GetRrcStatus().String()
+	c.mu.Lock()
+	c.SetUeRrcState(ctx, ueData, newRrcState, cgi, cgiObject)
+	c.mu.Unlock()
+
+	c.SetUe(ctx, ueData)
+	c.notifyUeChanged(ueData.UeID)
+
+}
+
+// getIndicationUe returns the UE the indication from the cell reports on, created and attached to the cell if it
+// is new, and whether it is new. It returns nil if the indication is ignored, see acceptIndication. The cells of
+// the measurements not known yet are created. It is called with the UE locked.
+func (c *Controller) getIndicationUe(ctx context.Context, ueID string, ueIdentity *e2sm_v2_ies.Ueid, cgi string, cgiObject *e2sm_v2_ies.Cgi, e2NodeID string, cgiTable map[string]*e2sm_v2_ies.Cgi) (*UeData, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	newUe := false
+	ueData := c.GetUe(ctx, ueID)
+	if ueData == nil {
+		ueData = c.CreateUe(ctx, ueID)
+		c.AttachUe(ctx, ueData, cgi, cgiObject)
+		newUe = true
+	} else if !c.acceptIndication(ctx, ueData, cgi) {
+		return nil, false
+	}
 
+	ueData.E2NodeID = e2NodeID
+	ueData.UeIdentity = ueIdentity
+	c.setCellE2Node(ctx, cgi, e2NodeID)
+	for measCGI, measCGIObject := range cgiTable {
+		if c.GetCell(ctx, measCGI) == nil {
+			_ = c.CreateCell(ctx, measCGI, measCGIObject)
+		}
+	}
+	ueData.Slice = c.getSlice(ueData)
+	return ueData, newUe
+}
+
+// getUeIDString returns the UE ID zero padded to the 16 digits of the UE store keys
+func getUeIDString(ueID int64) string {
 	ueIdString := strconv.Itoa(int(ueID))
 	n := (16 - len(ueIdString))
 	for i := 0; i < n; i++ {
 		ueIdString = "0" + ueIdString
 	}
-	var ueData *UeData
-	ueData = c.GetUe(ctx, ueIdString)
+	return ueIdString
+}
+
+// lockUe locks the UE against the concurrent updates of the indications and the handovers, the UEs share a fixed
+// number of locks. The UE is locked before the controller lock, the returned function unlocks it.
+func (c *Controller) lockUe(ueID string) func() {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(ueID))
+	mu := &c.ueLocks[hash.Sum32()%ueLockCount]
+	mu.Lock()
+	return mu.Unlock
+}
+
+// acceptIndication reports whether the indication received from the cell should update the UE. While a handover
+// is pending only the target cell is accepted, and its first indication confirms the handover.
+func (c *Controller) acceptIndication(ctx context.Context, ueData *UeData, cgi string) bool {
+	if ueData.Handover == nil {
+		return ueData.CGIString == cgi
+	}
+	if ueData.Handover.TargetCGIString != cgi {
+		return false
+	}
+	log.Infof("HANDOVER MESSAGE: UE [ID:%v] handover to CELL [CGI:%v] confirmed after %v\n", ueData.UeID, cgi, time.Since(ueData.Handover.Started).Round(time.Millisecond))
+	c.endHandover(ueData, &HandoverResult{
+		TargetCGIString: cgi,
+		Success:         true,
+		Reason:          "confirmed by the target cell",
+		Finished:        time.Now(),
+	})
+	c.SetUe(ctx, ueData)
+	return true
+}
+
+// StartHandover moves the UE to the target cell right away and keeps the handover pending until an indication
+// from the target cell confirms it. If that doesn't happen within the handover timeout the UE is moved back.
+// The UE is read with the UE locked so that the updates of the indications handled meanwhile are kept; it
+// returns the UE as handed over and the start time identifying the handover, or false if the UE is no longer known.
+func (c *Controller) StartHandover(ctx context.Context, ueID string, cgi string, cgiObject *e2sm_v2_ies.Cgi) (UeData, time.Time, bool) {
+	unlock := c.lockUe(ueID)
+	defer unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ueData := c.GetUe(ctx, ueID)
 	if ueData == nil {
-		ueData = c.CreateUe(ctx, ueIdString)
-		c.AttachUe(ctx, ueData, cgi, cgiObject)
-	} else if ueData.CGIString != cgi {
+		return UeData{}, time.Time{}, false
+	}
+	ueData.Idle = false
+	c.endHandover(ueData, nil)
+	started := time.Now()
+	ueData.Handover = &PendingHandover{
+		SourceCGIString: ueData.CGIString,
+		SourceCGI:       ueData.CGI,
+		TargetCGIString: cgi,
+		Started:         started,
+	}
+	c.handoverStats.Pending++
+	c.AttachUe(ctx, ueData, cgi, cgiObject)
+
+	timeout := c.handoverTimeout
+	if timeout <= 0 {
+		timeout = DefaultHandoverTimeout
+	}
+	// the timeout outlives the caller, so it runs with the context of the controller
+	controllerCtx := c.ctx
+	time.AfterFunc(timeout, func() {
+		c.FailHandover(controllerCtx, ueID, started, "not confirmed in time")
+	})
+	return *ueData, started, true
+}
+
+// AcknowledgeHandover records that the E2 node acknowledged the control request of the pending handover.
+func (c *Controller) AcknowledgeHandover(ctx context.Context, ueID string, started time.Time) {
+	unlock := c.lockUe(ueID)
+	defer unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ueData := c.GetUe(ctx, ueID)
+	if ueData == nil || ueData.Handover == nil || !ueData.Handover.Started.Equal(started) {
 		return
 	}
+	handover := *ueData.Handover
+	handover.Acknowledged = true
+	ueData.Handover = &handover
+	c.SetUe(ctx, ueData)
+}
 
-	ueData.E2NodeID = e2NodeID
+// FailHandover moves the UE back to its source cell if the handover started at the given time is still pending.
+func (c *Controller) FailHandover(ctx context.Context, ueID string, started time.Time, reason string) {
+	unlock := c.lockUe(ueID)
+	defer unlock()
+	c.mu.Lock()
 
-	newRrcState := message.GetRrcStatus().String()
-	c.SetUeRrcState(ctx, ueData, newRrcState, cgi, cgiObject)
+	ueData := c.GetUe(ctx, ueID)
+	if ueData == nil || ueData.Handover == nil || !ueData.Handover.Started.Equal(started) {
+		c.mu.Unlock()
+		return
+	}
+	handover := ueData.Handover
+	c.endHandover(ueData, &HandoverResult{
+		TargetCGIString: handover.TargetCGIString,
+		Success:         false,
+		Reason:          reason,
+		Finished:        time.Now(),
+	})
+	log.Warnf("HANDOVER MESSAGE: UE [ID:%v] handover to CELL [CGI:%v] failed - %v, UE back in CELL [CGI:%v]\n", ueID, handover.TargetCGIString, reason, handover.SourceCGIString)
+	if ueData.Idle {
+		c.SetUe(ctx, ueData)
+	} else {
+		c.AttachUe(ctx, ueData, handover.SourceCGIString, handover.SourceCGI)
+	}
+	c.mu.Unlock()
+	c.notifyUeChanged(ueID)
+}
 
-	c.SetUe(ctx, ueData)
+// endHandover clears the pending handover of the UE, if any, and records its result in the UE and the handover
+// stats. Without a result the handover is dropped, as when the UE is removed or handed over again. It must be
+// called with c.mu locked.
+func (c *Controller) endHandover(ueData *UeData, result *HandoverResult) {
+	if ueData.Handover == nil {
+		return
+	}
+	ueData.Handover = nil
+	c.handoverStats.Pending--
+	if result == nil {
+		return
+	}
+	ueData.LastHandover = result
+	if result.Success {
+		c.handoverStats.Succeeded++
+	} else {
+		c.handoverStats.Failed++
+	}
+}
 
+func (c *Controller) setCellE2Node(ctx context.Context, cgi string, e2NodeID string) {
+	cell := c.GetCell(ctx, cgi)
+	if cell != nil && cell.E2NodeID != e2NodeID {
+		cell.E2NodeID = e2NodeID
+		c.SetCell(ctx, cell)
+	}
+}
+
+// RemoveE2Node purges the UEs served by the disconnected E2 node and its cells, which are also removed from the
+// measurements of the other UEs so that they are no longer handover targets. It returns the removed UEs and the
+// CGIs of the removed cells.
+func (c *Controller) RemoveE2Node(ctx context.Context, e2NodeID string) ([]string, []string) {
+	removedCGIs := c.removeE2NodeCells(ctx, e2NodeID)
+
+	removedUes := make([]string, 0)
+	entries, err := GetStoreEntries(ctx, c.ueStore)
+	if err != nil {
+		log.Warn(err)
+		return removedUes, removedCGIs
+	}
+	for _, entry := range entries {
+		if c.removeE2NodeUe(ctx, entry.Key, e2NodeID, removedCGIs) {
+			removedUes = append(removedUes, entry.Key)
+		}
+	}
+	log.Infof("E2 node %v removed with %v UE(s) and %v CELL(s)", e2NodeID, len(removedUes), len(removedCGIs))
+	return removedUes, removedCGIs
+}
+
+// removeE2NodeCells removes the cells of the E2 node and returns their CGIs
+func (c *Controller) removeE2NodeCells(ctx context.Context, e2NodeID string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removedCGIs := make([]string, 0)
+	for cgi, cell := range c.cells {
+		if cell.E2NodeID == e2NodeID {
+			removedCGIs = append(removedCGIs, cgi)
+			if err := c.cellStore.Delete(ctx, cgi); err != nil {
+				log.Warn(err)
+			}
+			delete(c.cells, cgi)
+		}
+	}
+	return removedCGIs
+}
+
+// removeE2NodeUe removes the UE if the E2 node serves it, otherwise the removed cells from its measurements. It
+// reports whether the UE was removed.
+func (c *Controller) removeE2NodeUe(ctx context.Context, ueID string, e2NodeID string, removedCGIs []string) bool {
+	unlock := c.lockUe(ueID)
+	defer unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ueData := c.GetUe(ctx, ueID)
+	if ueData == nil {
+		return false
+	}
+	if ueData.E2NodeID == e2NodeID {
+		c.endHandover(ueData, nil)
+		c.DetachUe(ctx, ueData)
+		if err := c.ueStore.Delete(ctx, ueID); err != nil {
+			log.Warn(err)
+		}
+		return true
+	}
+	changed := false
+	for _, cgi := range removedCGIs {
+		if _, ok := ueData.RsrpTable[cgi]; ok {
+			delete(ueData.RsrpTable, cgi)
+			delete(ueData.RsrpNeighbors, cgi)
+			delete(ueData.CgiTable, cgi)
+			changed = true
+		}
+	}
+	if changed {
+		c.SetUe(ctx, ueData)
+	}
+	return false
+}
+
+func (c *Controller) SetHandoverTimeout(timeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handoverTimeout = timeout
+}
+
+func (c *Controller) GetHandoverStats() HandoverStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.handoverStats
 }
 
 func (c *Controller) CreateUe(ctx context.Context, ueID string) *UeData {
@@ -278,6 +648,7 @@ func (c *Controller) SetUeRrcState(ctx context.Context, ueData *UeData, newRrcSt
 		newRrcState == e2sm_mho.Rrcstatus_name[int32(e2sm_mho.Rrcstatus_RRCSTATUS_IDLE)] {
 		ueData.Idle = true
 		c.DetachUe(ctx, ueData)
+		c.notifyUeDetached(ueData.UeID)
 	} else if oldRrcState == e2sm_mho.Rrcstatus_name[int32(e2sm_mho.Rrcstatus_RRCSTATUS_IDLE)] &&
 		newRrcState == e2sm_mho.Rrcstatus_name[int32(e2sm_mho.Rrcstatus_RRCSTATUS_CONNECTED)] {
 		ueData.Idle = false
@@ -328,30 +699,66 @@ func (c *Controller) SetCell(ctx context.Context, cellData *CellData) {
 	c.cells[cellData.CGIString] = cellData
 }
 
-func (c *Controller) GetFiveQiFromMeasReport(ctx context.Context, servingNci uint64, measReport []*e2sm_mho.E2SmMhoMeasurementReportItem) int64 {
-	var fiveQiServing int64
+// GetQosFlowsFromMeasReport returns the QoS flows reported for the serving cell, in the order of the report
+// and without duplicates. The value is a QCI when the cell is an E-UTRA one and a 5QI otherwise.
+func (c *Controller) GetQosFlowsFromMeasReport(ctx context.Context, servingCGI string, measReport []*e2sm_mho.E2SmMhoMeasurementReportItem) []policyAPI.QosID {
+	qosFlows := make([]policyAPI.QosID, 0)
+	seen := make(map[string]bool)
 
 	for _, measReportItem := range measReport {
 
-		if GetNciFromCellGlobalID(measReportItem.GetCgi()) == servingNci {
+		if GetCGIFromMeasReportItem(measReportItem) == servingCGI {
 			fiveQi := measReportItem.GetFiveQi()
-			if fiveQi != nil {
-				fiveQiServing = int64(fiveQi.GetValue())
-				if fiveQiServing > 127 {
-					fiveQiServing = 2
-				} else {
-					fiveQiServing = 1
-				}
+			if fiveQi == nil {
+				continue
+			}
+			value := int64(fiveQi.GetValue())
+			qosFlow := policyAPI.QosID{}
+			if measReportItem.GetCgi().GetEUtraCgi() != nil {
+				qosFlow.QcI = &value
 			} else {
-				fiveQiServing = -1
+				qosFlow.The5QI = &value
+			}
+			key := QosFlowsToString([]policyAPI.QosID{qosFlow})
+			if !seen[key] {
+				seen[key] = true
+				qosFlows = append(qosFlows, qosFlow)
 			}
 		}
 	}
 
-	return fiveQiServing
+	return qosFlows
+}
+
+// GetFiveQiFromQosFlows returns the 5QI of the first NR QoS flow, -1 if there is none.
+func GetFiveQiFromQosFlows(qosFlows []policyAPI.QosID) int64 {
+	for _, qosFlow := range qosFlows {
+		if qosFlow.The5QI != nil {
+			return *qosFlow.The5QI
+		}
+	}
+	return -1
 }
 
-func (c *Controller) GetRsrpFromMeasReport(ctx context.Context, servingNci uint64, measReport []*e2sm_mho.E2SmMhoMeasurementReportItem) (int32, map[string]int32, map[string]int32, map[string]*e2sm_v2_ies.Cgi) {
+func QosFlowsToString(qosFlows []policyAPI.QosID) string {
+	values := make([]string, 0, len(qosFlows))
+	for _, qosFlow := range qosFlows {
+		if qosFlow.The5QI != nil {
+			values = append(values, fmt.Sprintf("5QI:%v", *qosFlow.The5QI))
+		}
+		if qosFlow.QcI != nil {
+			values = append(values, fmt.Sprintf("QCI:%v", *qosFlow.QcI))
+		}
+	}
+	if len(values) == 0 {
+		return "NONE"
+	}
+	return strings.Join(values, ",")
+}
+
+// GetRsrpFromMeasReport returns the RSRP of the serving cell, of the neighbor cells and of all the measured cells,
+// and the CGIs of the measured cells.
+func (c *Controller) GetRsrpFromMeasReport(ctx context.Context, servingCGI string, measReport []*e2sm_mho.E2SmMhoMeasurementReportItem) (int32, map[string]int32, map[string]int32, map[string]*e2sm_v2_ies.Cgi) {
 	var rsrpServing int32
 	rsrpNeighbors := make(map[string]int32)
 	rsrpTable := make(map[string]int32)
@@ -359,7 +766,7 @@ func (c *Controller) GetRsrpFromMeasReport(ctx context.Context, servingNci uint6
 
 	for _, measReportItem := range measReport {
 
-		if GetNciFromCellGlobalID(measReportItem.GetCgi()) == servingNci {
+		if GetCGIFromMeasReportItem(measReportItem) == servingCGI {
 			CGIString := GetCGIFromMeasReportItem(measReportItem)
 			CGIString = c.ConvertCgiToTheRightForm(CGIString)
 			rsrpServing = measReportItem.GetRsrp().GetValue()
@@ -371,17 +778,13 @@ func (c *Controller) GetRsrpFromMeasReport(ctx context.Context, servingNci uint6
 			rsrpNeighbors[CGIString] = measReportItem.GetRsrp().GetValue()
 			rsrpTable[CGIString] = measReportItem.GetRsrp().GetValue()
 			cgiTable[CGIString] = measReportItem.GetCgi()
-			cell := c.GetCell(ctx, CGIString)
-			if cell == nil {
-				_ = c.CreateCell(ctx, CGIString, measReportItem.GetCgi())
-			}
 		}
 	}
 
 	return rsrpServing, rsrpNeighbors, rsrpTable, cgiTable
 }
 
-func (c *Controller) CreatePolicy(ctx context.Context, key string, policy *policyAPI.API) *PolicyData {
+func (c *Controller) CreatePolicy(ctx context.Context, key string, policy *policyAPI.API, priority int) *PolicyData {
 	if len(key) == 0 {
 		panic("bad data")
 	}
@@ -389,6 +792,11 @@ func (c *Controller) CreatePolicy(ctx context.Context, key string, policy *polic
 		Key:        key,
 		API:        policy,
 		IsEnforced: true,
+		Priority:   priority,
+		CreatedAt:  time.Now(),
+	}
+	if existing, ok := c.policies[key]; ok {
+		policyData.CreatedAt = existing.CreatedAt
 	}
 	_, err := c.onosPolicyStore.Put(ctx, key, *policyData)
 	if err != nil {
@@ -433,9 +841,31 @@ func (c *Controller) GetPolicyStore() *store.Store {
 	return &c.onosPolicyStore
 }
 
+// GetStoreEntries returns all records of the store. The store sends the records to the channel while holding its
+// lock, so they are received while Entries runs in a goroutine of its own rather than into a bounded buffer.
+func GetStoreEntries(ctx context.Context, s store.Store) ([]*store.Entry, error) {
+	chEntries := make(chan *store.Entry)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.Entries(ctx, chEntries)
+	}()
+	entries := make([]*store.Entry, 0)
+	for entry := range chEntries {
+		entries = append(entries, entry)
+	}
+	return entries, <-errCh
+}
+
+// ConvertCgiToTheRightForm converts the NCGI from the indication to the topology form, if enabled.
+// The ECGIs are the same in both forms.
 func (c *Controller) ConvertCgiToTheRightForm(cgi string) string {
 	if c.topoIDsEnabled {
-		return cgi[0:6] + cgi[14:15] + cgi[12:14] + cgi[10:12] + cgi[8:10] + cgi[6:8]
+		cgiObject, err := ParseIndicationCGI(cgi)
+		if err != nil {
+			log.Warnf("Cannot convert CGI to the topology form: %v", err)
+			return cgi
+		}
+		return cgiObject.TopoString()
 	}
 	return cgi
 }
@@ -8,55 +8,101 @@ package mho
 
 import (
 	"fmt"
-	"strconv"
 
+	policyAPI "github.com/onosproject/onos-a1-dm/go/policy_schemas/traffic_steering_preference/v2"
 	e2sm_mho "github.com/onosproject/onos-e2-sm/servicemodels/e2sm_mho_go/v2/e2sm-mho-go"
 	e2sm_v2_ies "github.com/onosproject/onos-e2-sm/servicemodels/e2sm_mho_go/v2/e2sm-v2-ies"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 func PlmnIDBytesToInt(b []byte) uint64 {
 	return uint64(b[2])<<16 | uint64(b[1])<<8 | uint64(b[0])
 }
 
+// PlmnIDNciToCGI formats the NCGI in the form of the indications, see CGI.
 func PlmnIDNciToCGI(plmnID uint64, nci uint64) string {
-	cgi := strconv.FormatInt(int64(plmnID<<36|(nci&0xfffffffff)), 16)
-	return cgi
+	return fmt.Sprintf("%06x%09x", plmnID&0xffffff, nci&(1<<nciBits-1))
+}
+
+// PlmnIDEciToCGI formats the ECGI in the form of the indications, see CGI.
+func PlmnIDEciToCGI(plmnID uint64, eci uint64) string {
+	return fmt.Sprintf("%06x%07x", plmnID&0xffffff, eci&(1<<eciBits-1))
+}
+
+// IsEutraCellGlobalID tells whether the CGI is an ECGI rather than an NCGI.
+func IsEutraCellGlobalID(cellGlobalID *e2sm_v2_ies.Cgi) bool {
+	return cellGlobalID.GetEUtraCgi() != nil
 }
 
 func GetNciFromCellGlobalID(cellGlobalID *e2sm_v2_ies.Cgi) uint64 {
 	return BitStringToUint64(cellGlobalID.GetNRCgi().GetNRcellIdentity().GetValue().GetValue(), int(cellGlobalID.GetNRCgi().GetNRcellIdentity().GetValue().GetLen()))
 }
 
+func GetEciFromCellGlobalID(cellGlobalID *e2sm_v2_ies.Cgi) uint64 {
+	return BitStringToUint64(cellGlobalID.GetEUtraCgi().GetEUtracellIdentity().GetValue().GetValue(), int(cellGlobalID.GetEUtraCgi().GetEUtracellIdentity().GetValue().GetLen()))
+}
+
+// GetCellIdentityFromCellGlobalID returns the ECI of an ECGI or the NCI of an NCGI.
+func GetCellIdentityFromCellGlobalID(cellGlobalID *e2sm_v2_ies.Cgi) uint64 {
+	if IsEutraCellGlobalID(cellGlobalID) {
+		return GetEciFromCellGlobalID(cellGlobalID)
+	}
+	return GetNciFromCellGlobalID(cellGlobalID)
+}
+
 func GetPlmnIDBytesFromCellGlobalID(cellGlobalID *e2sm_v2_ies.Cgi) []byte {
+	if IsEutraCellGlobalID(cellGlobalID) {
+		return cellGlobalID.GetEUtraCgi().GetPLmnidentity().GetValue()
+	}
 	return cellGlobalID.GetNRCgi().GetPLmnidentity().GetValue()
 }
 
+// GetCGIFromCellGlobalID formats the ECGI or the NCGI in the indication form.
+func GetCGIFromCellGlobalID(cellGlobalID *e2sm_v2_ies.Cgi) string {
+	plmnID := PlmnIDBytesToInt(GetPlmnIDBytesFromCellGlobalID(cellGlobalID))
+	if IsEutraCellGlobalID(cellGlobalID) {
+		return PlmnIDEciToCGI(plmnID, GetEciFromCellGlobalID(cellGlobalID))
+	}
+	return PlmnIDNciToCGI(plmnID, GetNciFromCellGlobalID(cellGlobalID))
+}
+
+// GetCellIDFromCellGlobalID converts the CGI to the cell ID of the A1 policies, with the ECI or the NCI set.
+func GetCellIDFromCellGlobalID(cellGlobalID *e2sm_v2_ies.Cgi) (policyAPI.CellID, error) {
+	cgi, err := NewCGIFromCellGlobalID(cellGlobalID)
+	if err != nil {
+		return policyAPI.CellID{}, err
+	}
+	return cgi.CellID(), nil
+}
+
+// GetMccMncFromPlmnID decodes the BCD encoded PLMN ID, both are empty if it is not valid.
 func GetMccMncFromPlmnID(plmnId uint64) (string, string) {
-	plmnIdString := strconv.FormatUint(plmnId, 16)
-	return plmnIdString[0:3], plmnIdString[3:]
+	plmnID, err := PlmnIDFromEncoded(plmnId)
+	if err != nil {
+		log.Warn(err)
+		return "", ""
+	}
+	return plmnID.Mcc, plmnID.Mnc
 }
 
+// GetPlmnIdFromMccMnc encodes the MCC and the MNC as the BCD encoded PLMN ID.
 func GetPlmnIdFromMccMnc(mcc string, mnc string) (uint64, error) {
-	combined := mcc + mnc
-	plmnId, err := strconv.ParseUint(combined, 16, 64)
+	plmnID, err := NewPlmnID(mcc, mnc)
 	if err != nil {
-		log.Warn("Cannot convert PLMN ID string into uint64 type!")
+		log.Warnf("Cannot convert MCC and MNC into PLMN ID: %v", err)
+		return 0, err
 	}
-	return plmnId, err
+	return plmnID.Encoded(), nil
 }
 
 func GetCGIFromIndicationHeader(header *e2sm_mho.E2SmMhoIndicationHeaderFormat1) string {
-	nci := GetNciFromCellGlobalID(header.GetCgi())
-	plmnIDBytes := GetPlmnIDBytesFromCellGlobalID(header.GetCgi())
-	plmnID := PlmnIDBytesToInt(plmnIDBytes)
-	return PlmnIDNciToCGI(plmnID, nci)
+	return GetCGIFromCellGlobalID(header.GetCgi())
 }
 
 func GetCGIFromMeasReportItem(measReport *e2sm_mho.E2SmMhoMeasurementReportItem) string {
-	nci := GetNciFromCellGlobalID(measReport.GetCgi())
-	plmnIDBytes := GetPlmnIDBytesFromCellGlobalID(measReport.GetCgi())
-	plmnID := PlmnIDBytesToInt(plmnIDBytes)
-	return PlmnIDNciToCGI(plmnID, nci)
+	return GetCGIFromCellGlobalID(measReport.GetCgi())
 }
 
 func BitStringToUint64(bitString []byte, bitCount int) uint64 {
@@ -85,3 +131,85 @@ func GetUeID(ueID *e2sm_v2_ies.Ueid) (int64, error) {
 		return -1, fmt.Errorf("GetUeID() couldn't extract UeID - obtained unexpected type %v", ue)
 	}
 }
+
+// Field numbers of the formats of the encoded indication message and of their UE ID, from the generated descriptors
+var (
+	indicationMessageFormat1Field = getFieldNumber(&e2sm_mho.E2SmMhoIndicationMessage{}, "indication_message_format1")
+	indicationMessageFormat2Field = getFieldNumber(&e2sm_mho.E2SmMhoIndicationMessage{}, "indication_message_format2")
+	indicationMessageFormat1UeID  = getFieldNumber(&e2sm_mho.E2SmMhoIndicationMessageFormat1{}, "ue_id")
+	indicationMessageFormat2UeID  = getFieldNumber(&e2sm_mho.E2SmMhoIndicationMessageFormat2{}, "ue_id")
+)
+
+// getFieldNumber returns the number of the field of the message, it panics if the message has no such field
+func getFieldNumber(message proto.Message, name protoreflect.Name) protowire.Number {
+	field := message.ProtoReflect().Descriptor().Fields().ByName(name)
+	if field == nil {
+		panic(fmt.Sprintf("%v has no field %v", message.ProtoReflect().Descriptor().FullName(), name))
+	}
+	return field.Number()
+}
+
+// GetUeIDFromIndicationMessage returns the ID of the UE of the encoded indication message, decoding only the UE ID
+// and not the rest of the message. The fields are found by their numbers in the generated descriptors, so that the
+// UE ID follows the E2SM-MHO proto.
+func GetUeIDFromIndicationMessage(payload []byte) (int64, error) {
+	format, message, err := getMessageField(payload, indicationMessageFormat1Field, indicationMessageFormat2Field)
+	if err != nil {
+		return -1, fmt.Errorf("GetUeIDFromIndicationMessage() couldn't extract the indication message: %v", err)
+	}
+	ueIDField := indicationMessageFormat1UeID
+	if format == indicationMessageFormat2Field {
+		ueIDField = indicationMessageFormat2UeID
+	}
+	_, ueIDBytes, err := getMessageField(message, ueIDField)
+	if err != nil {
+		return -1, fmt.Errorf("GetUeIDFromIndicationMessage() couldn't extract UeID: %v", err)
+	}
+	ueID := &e2sm_v2_ies.Ueid{}
+	if err := proto.Unmarshal(ueIDBytes, ueID); err != nil {
+		return -1, fmt.Errorf("GetUeIDFromIndicationMessage() couldn't extract UeID: %v", err)
+	}
+	if ueID.Ueid == nil {
+		return -1, fmt.Errorf("GetUeIDFromIndicationMessage() couldn't extract UeID - obtained empty UeID")
+	}
+	return GetUeID(ueID)
+}
+
+// getMessageField returns the number and the value of the encoded message field with one of the numbers, the last
+// one like proto does when the field is repeated
+func getMessageField(b []byte, numbers ...protowire.Number) (protowire.Number, []byte, error) {
+	var value []byte
+	var number protowire.Number
+	found := false
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return 0, nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if typ == protowire.BytesType && containsNumber(numbers, num) {
+			value, n = protowire.ConsumeBytes(b)
+			number = num
+			found = true
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return 0, nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	if !found {
+		return 0, nil, fmt.Errorf("field %v not found", numbers)
+	}
+	return number, value, nil
+}
+
+func containsNumber(numbers []protowire.Number, number protowire.Number) bool {
+	for _, n := range numbers {
+		if n == number {
+			return true
+		}
+	}
+	return false
+}
@@ -10,6 +10,7 @@ package monitoring
 import (
 	"context"
 	"github.com/onosproject/rimedo-ts/pkg/mho"
+	"time"
 
 	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
 	topoapi "github.com/onosproject/onos-api/go/onos/topo"
@@ -36,19 +37,22 @@ type Monitor struct {
 	triggerType  e2sm_mho.MhoTriggerType
 }
 
+// Start forwards the indications of the stream until the stream fails or the context is done.
 func (m *Monitor) Start(ctx context.Context) error {
-	errCh := make(chan error)
+	errCh := make(chan error, 1)
 	go func() {
 		for {
 			indMsg, err := m.streamReader.Recv(ctx)
 			if err != nil {
 				log.Errorf("Error reading indication stream, chanID:%v, streamID:%v, err:%v", m.streamReader.ChannelID(), m.streamReader.StreamID(), err)
 				errCh <- err
+				return
 			}
 			err = m.processIndication(ctx, indMsg, m.nodeID)
 			if err != nil {
 				log.Errorf("Error processing indication, err:%v", err)
 				errCh <- err
+				return
 			}
 		}
 	}()
@@ -63,13 +67,19 @@ func (m *Monitor) Start(ctx context.Context) error {
 
 func (m *Monitor) processIndication(ctx context.Context, indication e2api.Indication, nodeID topoapi.ID) error {
 
-	m.indChan <- &mho.E2NodeIndication{
+	// a full indication channel blocks the stream until the controller catches up
+	select {
+	case m.indChan <- &mho.E2NodeIndication{
 		NodeID:      string(nodeID),
 		TriggerType: m.triggerType,
 		IndMsg: e2api.Indication{
 			Payload: indication.Payload,
 			Header:  indication.Header,
 		},
+		Timestamp: time.Now(),
+	}:
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 
 	return nil
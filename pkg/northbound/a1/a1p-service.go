@@ -17,6 +17,7 @@ import (
 	"github.com/google/uuid"
 	a1tapi "github.com/onosproject/onos-api/go/onos/a1t/a1"
 	"github.com/onosproject/onos-lib-go/pkg/logging/service"
+	"github.com/onosproject/rimedo-ts/pkg/policy"
 	"google.golang.org/grpc"
 )
 
@@ -95,16 +96,18 @@ var SampleNotEnforcedStatus = `
 
 var SampleNotEnforcedPolicyID = "2"
 
-func NewA1PService(policyMap *map[string][]byte, notifier chan bool) service.Service {
+func NewA1PService(policyMap *map[string][]byte, notifier chan bool, validator *policy.PolicySchemaValidatorV2) service.Service {
 	return &A1PService{
 		TsPolicyTypeMap: policyMap,
 		notifier:        notifier,
+		validator:       validator,
 	}
 }
 
 type A1PService struct {
 	TsPolicyTypeMap *map[string][]byte
 	notifier        chan bool
+	validator       *policy.PolicySchemaValidatorV2
 }
 
 func (a *A1PService) Register(s *grpc.Server) {
@@ -112,6 +115,7 @@ func (a *A1PService) Register(s *grpc.Server) {
 		TsPolicyTypeMap: *a.TsPolicyTypeMap,
 		StatusUpdateCh:  make(chan *a1tapi.PolicyStatusMessage),
 		notifier:        a.notifier,
+		validator:       a.validator,
 	}
 	a1tapi.RegisterPolicyServiceServer(s, server)
 }
@@ -120,9 +124,31 @@ type A1PServer struct {
 	TsPolicyTypeMap map[string][]byte
 	StatusUpdateCh  chan *a1tapi.PolicyStatusMessage
 	notifier        chan bool
+	validator       *policy.PolicySchemaValidatorV2
 	mu              sync.RWMutex
 }
 
+// rejectPolicy builds the result of a policy failing the schema validation
+func rejectPolicy(message *a1tapi.PolicyRequestMessage, err error) *a1tapi.PolicyResultMessage {
+	log.Warnf("Policy [ID:%v] rejected: %v", message.PolicyId, err)
+	return &a1tapi.PolicyResultMessage{
+		PolicyId:   message.PolicyId,
+		PolicyType: message.PolicyType,
+		Message: &a1tapi.ResultMessage{
+			Header: &a1tapi.Header{
+				PayloadType: message.Message.Header.PayloadType,
+				RequestId:   message.Message.Header.RequestId,
+				Encoding:    message.Message.Header.Encoding,
+				AppId:       message.Message.Header.AppId,
+			}, Payload: message.Message.Payload,
+			Result: &a1tapi.Result{
+				Success: false,
+				Reason:  fmt.Sprintf("Policy validation failed: %v", err),
+			},
+		},
+	}
+}
+
 func (a *A1PServer) PolicySetup(ctx context.Context, message *a1tapi.PolicyRequestMessage) (*a1tapi.PolicyResultMessage, error) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
@@ -169,6 +195,10 @@ func (a *A1PServer) PolicySetup(ctx context.Context, message *a1tapi.PolicyReque
 		return res, nil
 	}
 
+	if err := a.validator.ValidateV2(message.Message.Payload); err != nil {
+		return rejectPolicy(message, err), nil
+	}
+
 	a.TsPolicyTypeMap[message.PolicyId] = message.Message.Payload
 
 	go func() {
@@ -265,6 +295,10 @@ func (a *A1PServer) PolicyUpdate(ctx context.Context, message *a1tapi.PolicyRequ
 		return res, nil
 	}
 
+	if err := a.validator.ValidateV2(message.Message.Payload); err != nil {
+		return rejectPolicy(message, err), nil
+	}
+
 	a.TsPolicyTypeMap[message.PolicyId] = message.Message.Payload
 
 	go func() {
@@ -0,0 +1,144 @@
+// SPDX-FileCopyrightText: 2019-present Open Networking Foundation <info@opennetworking.org>
+// SPDX-FileCopyrightText: 2019-present Rimedo Labs
+//
+// SPDX-License-Identifier: Apache-2.0
+// Created by RIMEDO-Labs team
+
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"github.com/onosproject/onos-lib-go/pkg/logging/service"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+var log = logging.GetLogger("rimedo-ts", "state")
+
+// Section returns a JSON-serializable snapshot of a part of the xApp state.
+type Section func() interface{}
+
+// NewStateService creates the northbound service reporting the active xApp state.
+// The service is defined in api/onos/rimedots/state.proto, it only uses well-known
+// types so the descriptor below is written by hand: "/onos.rimedots.State/Get" takes
+// a google.protobuf.StringValue with a section name (empty for all sections) and
+// returns the section as a google.protobuf.Struct.
+func NewStateService() *StateService {
+	log.Debugf("State service created")
+	return &StateService{
+		sections: make(map[string]Section),
+	}
+}
+
+type StateService struct {
+	sections map[string]Section
+	mu       sync.RWMutex
+}
+
+// AddSection registers a named part of the state.
+func (s *StateService) AddSection(name string, section Section) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sections[name] = section
+}
+
+func (s *StateService) getSections() map[string]Section {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sections := make(map[string]Section, len(s.sections))
+	for name, section := range s.sections {
+		sections[name] = section
+	}
+	return sections
+}
+
+func (s *StateService) Register(r *grpc.Server) {
+	r.RegisterService(&stateServiceDesc, &StateServer{service: s})
+}
+
+type StateServer struct {
+	service *StateService
+}
+
+func (s *StateServer) Get(ctx context.Context, request *wrapperspb.StringValue) (*structpb.Struct, error) {
+	// the sections take locks of their own, they are called without holding the service lock
+	sections := s.service.getSections()
+
+	output := make(map[string]interface{})
+	if request.GetValue() == "" {
+		names := make([]string, 0, len(sections))
+		for name := range sections {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			output[name] = sections[name]()
+		}
+	} else {
+		section, ok := sections[request.GetValue()]
+		if !ok {
+			return nil, errors.Status(errors.NewNotFound("unknown state section %v", request.GetValue())).Err()
+		}
+		output[request.GetValue()] = section()
+	}
+
+	return toStruct(output)
+}
+
+func toStruct(value interface{}) (*structpb.Struct, error) {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		log.Warn(err)
+		return nil, errors.Status(errors.NewInternal("%v", err)).Err()
+	}
+	fields := make(map[string]interface{})
+	if err = json.Unmarshal(bytes, &fields); err != nil {
+		log.Warn(err)
+		return nil, errors.Status(errors.NewInternal("%v", err)).Err()
+	}
+	return structpb.NewStruct(fields)
+}
+
+type stateServer interface {
+	Get(context.Context, *wrapperspb.StringValue) (*structpb.Struct, error)
+}
+
+func getHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(wrapperspb.StringValue)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(stateServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/onos.rimedots.State/Get",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(stateServer).Get(ctx, req.(*wrapperspb.StringValue))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var stateServiceDesc = grpc.ServiceDesc{
+	ServiceName: "onos.rimedots.State",
+	HandlerType: (*stateServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Get",
+			Handler:    getHandler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "onos/rimedots/state.proto",
+}
+
+var _ service.Service = &StateService{}
@@ -12,9 +12,14 @@ import (
 	"io/ioutil"
 	"math"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 
 	policyAPI "github.com/onosproject/onos-a1-dm/go/policy_schemas/traffic_steering_preference/v2"
 	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"github.com/onosproject/rimedo-ts/pkg/config"
 	"github.com/onosproject/rimedo-ts/pkg/mho"
 	"github.com/xeipuuv/gojsonschema"
 )
@@ -23,38 +28,152 @@ var log = logging.GetLogger("rimedo-ts", "policy")
 
 func NewPolicySchemaValidatorV2(path string) *PolicySchemaValidatorV2 {
 
-	return &PolicySchemaValidatorV2{
+	validator := &PolicySchemaValidatorV2{
 		schemePath: path,
 	}
 
+	if path != "" {
+		if _, err := os.Stat(path); err == nil {
+			schema, err := gojsonschema.NewSchema(gojsonschema.NewReferenceLoader("file://" + path))
+			if err == nil {
+				validator.schema = schema
+				return validator
+			}
+			log.Warnf("Couldn't load policy schema from %v, falling back to the embedded one: %v", path, err)
+		} else {
+			log.Warnf("Policy schema %v not available, falling back to the embedded one", path)
+		}
+	}
+
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(policyAPI.RawSchema))
+	if err != nil {
+		log.Panic("Couldn't load embedded policy schema: ", err)
+	}
+	validator.schema = schema
+	return validator
+
 }
 
 type PolicySchemaValidatorV2 struct {
 	schemePath string
+	schema     *gojsonschema.Schema
 }
 
-func NewPolicyManager(policyMap *map[string]*mho.PolicyData) *PolicyManager {
+// ValidateV2 checks a raw policy payload against the ORAN_TrafficSteeringPreference
+// schema and returns an error describing every violation found.
+func (v *PolicySchemaValidatorV2) ValidateV2(payload []byte) error {
 
-	var POLICY_WEIGHTS = map[string]int{
-		"DEFAULT": 0.0,
-		"PREFER":  16.0,
-		"AVOID":   -16.0,
-		"SHALL":   1000.0,
-		"FORBID":  -1000.0,
+	result, err := v.schema.Validate(gojsonschema.NewBytesLoader(payload))
+	if err != nil {
+		return fmt.Errorf("policy is not a valid JSON document: %v", err)
+	}
+	if !result.Valid() {
+		reasons := make([]string, 0, len(result.Errors()))
+		for _, resultErr := range result.Errors() {
+			reasons = append(reasons, resultErr.String())
+		}
+		return fmt.Errorf("policy does not match the schema: %v", strings.Join(reasons, "; "))
+	}
+	if _, err = policyAPI.UnmarshalAPI(payload); err != nil {
+		return fmt.Errorf("policy can't be unmarshaled: %v", err)
 	}
+	return nil
+}
+
+func NewPolicyManager(policyMap *map[string]*mho.PolicyData, schemePath string) *PolicyManager {
 
 	return &PolicyManager{
-		validator:     NewPolicySchemaValidatorV2("schemePath"),
-		policyMap:     policyMap,
-		preferenceMap: POLICY_WEIGHTS,
+		validator:      NewPolicySchemaValidatorV2(schemePath),
+		policyMap:      policyMap,
+		scoring:        config.DefaultScoring(),
+		sliceScoring:   make(map[string]config.Scoring),
+		notEnforceable: make(map[string]map[string]bool),
+		mu:             sync.RWMutex{},
 	}
 
 }
 
 type PolicyManager struct {
-	validator     *PolicySchemaValidatorV2
-	policyMap     *map[string]*mho.PolicyData
-	preferenceMap map[string]int
+	validator      *PolicySchemaValidatorV2
+	policyMap      *map[string]*mho.PolicyData
+	scoring        config.Scoring
+	sliceScoring   map[string]config.Scoring
+	notEnforceable map[string]map[string]bool
+	mu             sync.RWMutex
+}
+
+// ScoringFunctions combine the RSRP of a cell and the weight of its preference into a score.
+var ScoringFunctions = map[string]func(scoring config.Scoring, weight float64, rsrp int) float64{
+	// additive adds the preference weight, given in dB, to the RSRP
+	"additive": func(scoring config.Scoring, weight float64, rsrp int) float64 {
+		return float64(rsrp) + weight
+	},
+	// linear is a weighted sum of the RSRP and the preference weight
+	"linear": func(scoring config.Scoring, weight float64, rsrp int) float64 {
+		return scoring.RsrpCoefficient*float64(rsrp) + scoring.PreferenceCoefficient*weight
+	},
+	// preference-first ranks cells by preference and uses the RSRP only between equally preferred cells
+	"preference-first": func(scoring config.Scoring, weight float64, rsrp int) float64 {
+		return weight*1e6 + float64(rsrp)
+	},
+}
+
+// SetScoringV2 replaces the active scoring with the deployment-wide and per-slice settings.
+func (m *PolicyManager) SetScoringV2(scoring config.Scoring, sliceScoring map[string]config.Scoring) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scoring = validateScoring("default", scoring)
+	m.sliceScoring = make(map[string]config.Scoring, len(sliceScoring))
+	for slice, s := range sliceScoring {
+		m.sliceScoring[slice] = validateScoring(slice, s)
+	}
+}
+
+// ActiveScoring is the scoring currently used by the steering logic.
+type ActiveScoring struct {
+	Default config.Scoring            `json:"default"`
+	Slices  map[string]config.Scoring `json:"slices"`
+}
+
+func (m *PolicyManager) GetScoringV2() ActiveScoring {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	active := ActiveScoring{
+		Default: m.scoring.Copy(),
+		Slices:  make(map[string]config.Scoring, len(m.sliceScoring)),
+	}
+	for slice, s := range m.sliceScoring {
+		active.Slices[slice] = s.Copy()
+	}
+	return active
+}
+
+// GetScoringForSliceV2 returns the scoring of the slice, or the deployment-wide one if the slice has no override.
+func (m *PolicyManager) GetScoringForSliceV2(sliceID *policyAPI.SliceID) config.Scoring {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if sliceID != nil {
+		key := strconv.FormatInt(sliceID.Sst, 10)
+		if sliceID.SD != nil && *sliceID.SD != "" {
+			key = key + ":" + strings.ToUpper(*sliceID.SD)
+		}
+		if s, ok := m.sliceScoring[key]; ok {
+			return s
+		}
+	}
+	return m.scoring
+}
+
+func validateScoring(name string, scoring config.Scoring) config.Scoring {
+	if _, ok := ScoringFunctions[scoring.Function]; !ok {
+		log.Warnf("Unknown scoring function %v for %v scoring, using additive", scoring.Function, name)
+		scoring.Function = "additive"
+	}
+	return scoring.Copy()
+}
+
+func (m *PolicyManager) GetValidatorV2() *PolicySchemaValidatorV2 {
+	return m.validator
 }
 
 func (m *PolicyManager) ReadPolicyObjectFromFileV2(jsonPath string, policyObject *mho.PolicyData) error {
@@ -98,22 +217,16 @@ func (m *PolicyManager) CheckPerUePolicyV2(ueScope policyAPI.Scope, policyObject
 
 	if (policyObject.API.Scope.SliceID != nil) && (((policyObject.API.Scope.SliceID.SD == nil || (policyObject.API.Scope.SliceID.SD != nil && *policyObject.API.Scope.SliceID.SD == "")) ||
 		policyObject.API.Scope.SliceID.Sst <= 0 || policyObject.API.Scope.SliceID.PlmnID.Mcc == "" || policyObject.API.Scope.SliceID.PlmnID.Mnc == "") ||
-		(*policyObject.API.Scope.SliceID.SD != *ueScope.SliceID.SD || policyObject.API.Scope.SliceID.Sst != ueScope.SliceID.Sst ||
-			policyObject.API.Scope.SliceID.PlmnID.Mcc != ueScope.SliceID.PlmnID.Mcc || policyObject.API.Scope.SliceID.PlmnID.Mnc != ueScope.SliceID.PlmnID.Mnc)) {
+		!IsSameSliceV2(policyObject.API.Scope.SliceID, ueScope.SliceID)) {
 		return false
 	}
 
-	if (policyObject.API.Scope.QosID != nil) && ((policyObject.API.Scope.QosID.QcI == nil && policyObject.API.Scope.QosID.The5QI == nil) ||
-		(policyObject.API.Scope.QosID.QcI != nil && *policyObject.API.Scope.QosID.QcI != *ueScope.QosID.QcI) ||
-		(policyObject.API.Scope.QosID.The5QI != nil && *policyObject.API.Scope.QosID.The5QI != *ueScope.QosID.The5QI)) {
+	if (policyObject.API.Scope.QosID != nil) && !IsSameQosV2(policyObject.API.Scope.QosID, ueScope.QosID) {
 		return false
 	}
 
-	if (policyObject.API.Scope.CellID != nil) && (((policyObject.API.Scope.CellID.CID.NcI == nil && policyObject.API.Scope.CellID.CID.EcI == nil) ||
-		(policyObject.API.Scope.CellID.CID.NcI != nil && *policyObject.API.Scope.CellID.CID.NcI != *ueScope.CellID.CID.NcI) ||
-		(policyObject.API.Scope.CellID.CID.EcI != nil && *policyObject.API.Scope.CellID.CID.EcI != *ueScope.CellID.CID.EcI)) ||
-		((policyObject.API.Scope.CellID.PlmnID.Mcc == "" || policyObject.API.Scope.CellID.PlmnID.Mnc == "") ||
-			(policyObject.API.Scope.CellID.PlmnID.Mcc != ueScope.CellID.PlmnID.Mcc || policyObject.API.Scope.CellID.PlmnID.Mnc != ueScope.CellID.PlmnID.Mnc))) {
+	if (policyObject.API.Scope.CellID != nil) && ((policyObject.API.Scope.CellID.PlmnID.Mcc == "" || policyObject.API.Scope.CellID.PlmnID.Mnc == "") ||
+		ueScope.CellID == nil || !IsSameCellV2(*policyObject.API.Scope.CellID, *ueScope.CellID)) {
 		return false
 	}
 
@@ -126,73 +239,244 @@ func (m *PolicyManager) CheckPerSlicePolicyV2(ueScope policyAPI.Scope, policyObj
 		return false
 	}
 
-	if (policyObject.API.Scope.SliceID != nil && *policyObject.API.Scope.SliceID.SD == "") ||
+	if (policyObject.API.Scope.SliceID.SD == nil || *policyObject.API.Scope.SliceID.SD == "") ||
 		policyObject.API.Scope.SliceID.Sst <= 0 ||
 		policyObject.API.Scope.SliceID.PlmnID.Mcc == "" ||
 		policyObject.API.Scope.SliceID.PlmnID.Mnc == "" {
 		return false
 	}
 
+	if !IsSameSliceV2(policyObject.API.Scope.SliceID, ueScope.SliceID) {
+		return false
+	}
+
 	if (policyObject.API.Scope.UeID != nil) && !((*policyObject.API.Scope.UeID == "") || (*policyObject.API.Scope.UeID == *ueScope.UeID)) {
 		return false
 	}
 
-	if (policyObject.API.Scope.QosID != nil) && ((policyObject.API.Scope.QosID.QcI == nil && policyObject.API.Scope.QosID.The5QI == nil) ||
-		(policyObject.API.Scope.QosID.QcI != nil && *policyObject.API.Scope.QosID.QcI != *ueScope.QosID.QcI) ||
-		(policyObject.API.Scope.QosID.The5QI != nil && *policyObject.API.Scope.QosID.The5QI != *ueScope.QosID.The5QI)) {
+	if (policyObject.API.Scope.QosID != nil) && !IsSameQosV2(policyObject.API.Scope.QosID, ueScope.QosID) {
 		return false
 	}
 
-	if (policyObject.API.Scope.CellID != nil) && (((policyObject.API.Scope.CellID.CID.NcI == nil && policyObject.API.Scope.CellID.CID.EcI == nil) ||
-		(policyObject.API.Scope.CellID.CID.NcI != nil && *policyObject.API.Scope.CellID.CID.NcI != *ueScope.CellID.CID.NcI) ||
-		(policyObject.API.Scope.CellID.CID.EcI != nil && *policyObject.API.Scope.CellID.CID.EcI != *ueScope.CellID.CID.EcI)) ||
-		((policyObject.API.Scope.CellID.PlmnID.Mcc == "" || policyObject.API.Scope.CellID.PlmnID.Mnc == "") ||
-			(policyObject.API.Scope.CellID.PlmnID.Mcc != ueScope.CellID.PlmnID.Mcc || policyObject.API.Scope.CellID.PlmnID.Mnc != ueScope.CellID.PlmnID.Mnc))) {
+	if (policyObject.API.Scope.CellID != nil) && ((policyObject.API.Scope.CellID.PlmnID.Mcc == "" || policyObject.API.Scope.CellID.PlmnID.Mnc == "") ||
+		ueScope.CellID == nil || !IsSameCellV2(*policyObject.API.Scope.CellID, *ueScope.CellID)) {
 		return false
 	}
 
 	return true
 }
 
-func (m *PolicyManager) GetTsResultForUEV2(ueScope policyAPI.Scope, rsrps []int, cellIds []policyAPI.CellID) policyAPI.CellID {
+// GetTsResultForUEV2 returns the best scored cell the UE is allowed to use and the scores of all cells,
+// math.Inf(-1) for the cells that are not allowed, see GetConstraintsV2. When the constraints leave no candidate
+// the last result is false and the UE should stay in its cell.
+// The loads are the ratios of the UEs each cell would serve with the UE attached to its capacity, 0 if unknown.
+func (m *PolicyManager) GetTsResultForUEV2(ueScope policyAPI.Scope, rsrps []int, loads []float64, cellIds []policyAPI.CellID) (policyAPI.CellID, []float64, bool) {
+	return m.GetTsResultForPoliciesV2(ueScope, m.GetMatchingPoliciesV2(ueScope), rsrps, loads, cellIds)
+}
+
+// GetTsResultForPoliciesV2 is GetTsResultForUEV2 with the policies applicable to the UE already matched,
+// ordered from the highest to the lowest precedence.
+func (m *PolicyManager) GetTsResultForPoliciesV2(ueScope policyAPI.Scope, matching []*mho.PolicyData, rsrps []int, loads []float64, cellIds []policyAPI.CellID) (policyAPI.CellID, []float64, bool) {
+
+	scoring := m.GetScoringForSliceV2(ueScope.SliceID)
+	allowed, _, _ := GetConstraintsV2(matching, cellIds)
 
 	var bestCell policyAPI.CellID
 	bestScore := -math.MaxFloat64
+	found := false
+	scores := make([]float64, len(rsrps))
 	for i := 0; i < len(rsrps); i++ {
-		preferece := m.GetPreferenceV2(ueScope, cellIds[i])
-		score := m.GetPreferenceScoresV2(preferece, rsrps[i])
+		scores[i] = math.Inf(-1)
+		if !allowed[i] {
+			continue
+		}
+		preferece, _ := getPreferenceFromPolicies(matching, cellIds[i])
+		score := GetLoadedScoreV2(scoring, preferece, rsrps[i], loads[i])
+		scores[i] = score
 		if score > bestScore {
 			bestCell = cellIds[i]
 			bestScore = score
+			found = true
+		}
+	}
+
+	return bestCell, scores, found
+}
+
+// GetConstraintsV2 applies the SHALL and FORBID preferences of the matching policies, ordered from the highest to
+// the lowest precedence, to the candidate cells. FORBID cells are never allowed and if SHALL is the winning
+// preference of any cell, only the SHALL cells are. It returns which cells are allowed, the policies whose
+// constraints applied and whether any cell is allowed.
+func GetConstraintsV2(matching []*mho.PolicyData, cellIds []policyAPI.CellID) ([]bool, []*mho.PolicyData, bool) {
+
+	constraining := make(map[string]*mho.PolicyData)
+	shallRequired := false
+	// a SHALL cell only constrains the UE if no policy of a higher precedence assigns the cell another preference
+	for _, policy := range matching {
+		for _, tspResource := range policy.API.TSPResources {
+			if tspResource.Preference != policyAPI.Shall {
+				continue
+			}
+			for _, cellId := range tspResource.CellIDList {
+				if preference, winner := getPreferenceFromPolicies(matching, cellId); preference == string(policyAPI.Shall) {
+					shallRequired = true
+					constraining[winner.Key] = winner
+				}
+			}
+		}
+	}
+
+	feasible := false
+	allowed := make([]bool, len(cellIds))
+	for i := range cellIds {
+		preferece, policy := getPreferenceFromPolicies(matching, cellIds[i])
+		if preferece == string(policyAPI.Forbid) {
+			constraining[policy.Key] = policy
+			continue
+		}
+		if shallRequired && preferece != string(policyAPI.Shall) {
+			continue
 		}
+		allowed[i] = true
+		feasible = true
 	}
-	return bestCell
+
+	policies := make([]*mho.PolicyData, 0, len(constraining))
+	for _, policy := range matching {
+		if _, ok := constraining[policy.Key]; ok {
+			policies = append(policies, policy)
+		}
+	}
+	return allowed, policies, feasible
+}
+
+// SetEnforceableV2 records whether the constraining policies can be enforced for the UE, see GetConstraintsV2. The
+// UE is no longer reported for the policies that don't constrain it anymore.
+func (m *PolicyManager) SetEnforceableV2(ueID string, constraining []*mho.PolicyData, enforceable bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	constrainingKeys := make(map[string]bool, len(constraining))
+	for _, policy := range constraining {
+		constrainingKeys[policy.Key] = true
+	}
+	for key, ues := range m.notEnforceable {
+		if (!constrainingKeys[key] || enforceable) && ues[ueID] {
+			delete(ues, ueID)
+			log.Infof("POLICY MESSAGE: Policy [ID:%v] enforceable again for UE [ID:%v]\n", key, ueID)
+		}
+	}
+	if enforceable {
+		return
+	}
+	for key := range constrainingKeys {
+		if _, ok := m.notEnforceable[key]; !ok {
+			m.notEnforceable[key] = make(map[string]bool)
+		}
+		if !m.notEnforceable[key][ueID] {
+			m.notEnforceable[key][ueID] = true
+			log.Infof("POLICY MESSAGE: Policy [ID:%v] not enforceable for UE [ID:%v] - no cell satisfies SHALL/FORBID, UE stays in its cell\n", key, ueID)
+		}
+	}
+}
+
+// ForgetPolicyV2 drops the UEs the deleted policy wasn't enforceable for.
+func (m *PolicyManager) ForgetPolicyV2(policyId string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.notEnforceable, policyId)
+}
+
+// GetNotEnforceableUesV2 returns the UEs for which the policy constraints can't be satisfied.
+func (m *PolicyManager) GetNotEnforceableUesV2(policyId string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ues := make([]string, 0, len(m.notEnforceable[policyId]))
+	for ue := range m.notEnforceable[policyId] {
+		ues = append(ues, ue)
+	}
+	sort.Strings(ues)
+	return ues
 }
 
 func (m *PolicyManager) GetPreferenceScoresV2(preference string, rsrp int) float64 {
-	return float64(rsrp) + float64(m.preferenceMap[preference])
+	return GetScoreV2(m.GetScoringForSliceV2(nil), preference, rsrp)
+}
+
+func GetScoreV2(scoring config.Scoring, preference string, rsrp int) float64 {
+	return ScoringFunctions[scoring.Function](scoring, scoring.Weights[preference], rsrp)
+}
+
+// GetLoadedScoreV2 subtracts the load term from the score of the cell. A cell over its capacity also loses
+// the bonus of a positive preference weight, so a full PREFER cell doesn't attract more UEs.
+func GetLoadedScoreV2(scoring config.Scoring, preference string, rsrp int, load float64) float64 {
+	weight := scoring.Weights[preference]
+	if load > 1 && weight > 0 {
+		weight = 0
+	}
+	return ScoringFunctions[scoring.Function](scoring, weight, rsrp) - scoring.LoadCoefficient*load
 }
 
 func (m *PolicyManager) GetPreferenceV2(ueScope policyAPI.Scope, queryCellId policyAPI.CellID) string {
 
-	var preference string = "DEFAULT"
+	preference, _ := getPreferenceFromPolicies(m.GetMatchingPoliciesV2(ueScope), queryCellId)
+	return preference
+}
+
+// GetPreferenceFromPoliciesV2 returns the preference the already matched policies assign to the cell.
+func (m *PolicyManager) GetPreferenceFromPoliciesV2(policies []*mho.PolicyData, queryCellId policyAPI.CellID) string {
+
+	preference, _ := getPreferenceFromPolicies(policies, queryCellId)
+	return preference
+}
+
+func getPreferenceFromPolicies(policies []*mho.PolicyData, queryCellId policyAPI.CellID) (string, *mho.PolicyData) {
+
+	for _, policy := range policies {
+		if preference, ok := GetCellPreferenceV2(policy, queryCellId); ok {
+			return string(preference), policy
+		}
+	}
+	return "DEFAULT", nil
+}
+
+// GetMatchingPoliciesV2 returns the enforced policies applicable to the UE scope,
+// ordered from the highest to the lowest precedence.
+func (m *PolicyManager) GetMatchingPoliciesV2(ueScope policyAPI.Scope) []*mho.PolicyData {
+
+	matching := make([]*mho.PolicyData, 0)
 	for _, policy := range *m.policyMap {
 		if policy.IsEnforced {
 			if m.CheckPerSlicePolicyV2(ueScope, policy) || m.CheckPerUePolicyV2(ueScope, policy) {
-				for _, tspResource := range policy.API.TSPResources {
-
-					for _, cellId := range tspResource.CellIDList {
-						if ((cellId.CID.NcI != nil && queryCellId.CID.NcI != nil && *cellId.CID.NcI == *queryCellId.CID.NcI) ||
-							(cellId.CID.EcI != nil && queryCellId.CID.EcI != nil && *cellId.CID.EcI == *queryCellId.CID.EcI)) &&
-							(cellId.PlmnID.Mcc == queryCellId.PlmnID.Mcc && cellId.PlmnID.Mnc == queryCellId.PlmnID.Mnc) {
-							preference = string(tspResource.Preference)
-						}
-					}
-				}
+				matching = append(matching, policy)
 			}
 		}
 	}
-	return preference
+	SortByPrecedenceV2(matching)
+	return matching
+}
+
+// GetMatchingPoliciesForFlowsV2 returns the enforced policies applicable to the UE scope with any of the QoS flows
+// of the UE, ordered from the highest to the lowest precedence. The QoS of the scope is ignored.
+func (m *PolicyManager) GetMatchingPoliciesForFlowsV2(ueScope policyAPI.Scope, qosFlows []policyAPI.QosID) []*mho.PolicyData {
+
+	matching := make([]*mho.PolicyData, 0)
+	for _, policy := range *m.policyMap {
+		if !policy.IsEnforced {
+			continue
+		}
+		flowScope := ueScope
+		flowScope.QosID = nil
+		matched := m.CheckPerSlicePolicyV2(flowScope, policy) || m.CheckPerUePolicyV2(flowScope, policy)
+		for i := 0; !matched && i < len(qosFlows); i++ {
+			flowScope.QosID = &qosFlows[i]
+			matched = m.CheckPerSlicePolicyV2(flowScope, policy) || m.CheckPerUePolicyV2(flowScope, policy)
+		}
+		if matched {
+			matching = append(matching, policy)
+		}
+	}
+	SortByPrecedenceV2(matching)
+	return matching
 }
 
 func (m *PolicyManager) AddPolicyV2(policyId string, policyDir string, policyObject *mho.PolicyData) (*mho.PolicyData, error) {
@@ -237,10 +521,9 @@ func (m *PolicyManager) GetPolicyV2(policyId string) (*mho.PolicyData, bool) {
 
 func (m *PolicyManager) ValidatePolicyJsonSchemaV2(jsonPath string) (bool, error) {
 
-	schemaLoader := gojsonschema.NewReferenceLoader("file://" + m.validator.schemePath)
 	documentLoader := gojsonschema.NewReferenceLoader("file://" + jsonPath)
 
-	result, err := gojsonschema.Validate(schemaLoader, documentLoader)
+	result, err := m.validator.schema.Validate(documentLoader)
 	if err != nil {
 		return false, err
 	}
@@ -276,87 +559,3 @@ func (m *PolicyManager) LoadPolicyJsonFromFileV2(path string) ([]byte, error) {
 	return byteValue, nil
 
 }
-
-//func (m *PolicyManager) isSimilarEnforced(policyData *mho.PolicyData) bool {
-//	for _, policy := range *m.policyMap {
-//
-//		sameSlice := false
-//		sameUE := false
-//		sameQoS := false
-//		sameCellID := false
-//
-//		if (policyData.API.Scope.SliceID == nil && policy.API.Scope.SliceID == nil) ||
-//			(policyData.API.Scope.SliceID != nil && policy.API.Scope.SliceID != nil &&
-//				policy.API.Scope.SliceID.Sst == policyData.API.Scope.SliceID.Sst &&
-//				policy.API.Scope.SliceID.SD != nil && policyData.API.Scope.SliceID.SD != nil &&
-//				*policy.API.Scope.SliceID.SD == *policyData.API.Scope.SliceID.SD &&
-//				policy.API.Scope.SliceID.PlmnID.Mcc == policyData.API.Scope.SliceID.PlmnID.Mcc &&
-//				policy.API.Scope.SliceID.PlmnID.Mnc == policyData.API.Scope.SliceID.PlmnID.Mnc) {
-//			sameSlice = true
-//		}
-//
-//		if (policyData.API.Scope.UeID == nil && policy.API.Scope.UeID == nil) ||
-//			(policyData.API.Scope.UeID != nil && policy.API.Scope.UeID != nil && *policy.API.Scope.UeID == *policyData.API.Scope.UeID) {
-//			sameUE = true
-//		}
-//
-//		if (policyData.API.Scope.QosID == nil && policy.API.Scope.QosID == nil) ||
-//			(policyData.API.Scope.QosID != nil && policy.API.Scope.QosID != nil &&
-//				((policy.API.Scope.QosID.QcI != nil && policyData.API.Scope.QosID.QcI != nil &&
-//					*policy.API.Scope.QosID.QcI == *policyData.API.Scope.QosID.QcI) ||
-//					(policy.API.Scope.QosID.The5QI != nil && policyData.API.Scope.QosID.The5QI != nil &&
-//						*policy.API.Scope.QosID.The5QI == *policyData.API.Scope.QosID.The5QI))) {
-//			sameQoS = true
-//		}
-//
-//		if (policyData.API.Scope.CellID == nil && policy.API.Scope.CellID == nil) ||
-//			(policyData.API.Scope.CellID != nil && policy.API.Scope.CellID != nil &&
-//				((policy.API.Scope.CellID.CID.NcI != nil && policyData.API.Scope.CellID.CID.NcI != nil &&
-//					*policy.API.Scope.CellID.CID.NcI == *policyData.API.Scope.CellID.CID.NcI) ||
-//					(policy.API.Scope.CellID.CID.EcI != nil && policyData.API.Scope.CellID.CID.EcI != nil &&
-//						*policy.API.Scope.CellID.CID.EcI == *policyData.API.Scope.CellID.CID.EcI)) &&
-//				policy.API.Scope.CellID.PlmnID.Mcc == policyData.API.Scope.CellID.PlmnID.Mcc &&
-//				policy.API.Scope.CellID.PlmnID.Mnc == policyData.API.Scope.CellID.PlmnID.Mnc) {
-//			sameCellID = true
-//		}
-//
-//		if sameSlice {
-//			if sameUE {
-//				if sameQoS {
-//					if !sameCellID {
-//						continue
-//					}
-//				} else {
-//					continue
-//				}
-//			} else {
-//				if sameQoS {
-//					if !sameCellID {
-//						continue
-//					}
-//				} else {
-//					continue
-//				}
-//			}
-//		} else {
-//			if sameUE {
-//				if sameQoS {
-//					if !sameCellID {
-//						continue
-//					}
-//				} else {
-//					continue
-//				}
-//			} else {
-//				continue
-//			}
-//		}
-//
-//		if policy.IsEnforced {
-//			policy.IsEnforced = false
-//			return true
-//		}
-//
-//	}
-//	return false
-//}
@@ -0,0 +1,214 @@
+// SPDX-FileCopyrightText: 2019-present Open Networking Foundation <info@opennetworking.org>
+// SPDX-FileCopyrightText: 2019-present Rimedo Labs
+//
+// SPDX-License-Identifier: Apache-2.0
+// Created by RIMEDO-Labs team
+package policy
+
+import (
+	"sort"
+	"strings"
+
+	policyAPI "github.com/onosproject/onos-a1-dm/go/policy_schemas/traffic_steering_preference/v2"
+	"github.com/onosproject/rimedo-ts/pkg/mho"
+)
+
+// ScopeRank orders policy scopes from the least to the most specific one.
+type ScopeRank int
+
+const (
+	ScopeRankCell ScopeRank = iota
+	ScopeRankSlice
+	ScopeRankSliceQos
+	ScopeRankUe
+)
+
+func (r ScopeRank) String() string {
+	switch r {
+	case ScopeRankUe:
+		return "UE"
+	case ScopeRankSliceQos:
+		return "SLICE+QOS"
+	case ScopeRankSlice:
+		return "SLICE"
+	default:
+		return "CELL"
+	}
+}
+
+// GetScopeRankV2 classifies the policy scope: UE > slice+QoS > slice > cell.
+func GetScopeRankV2(policyObject *mho.PolicyData) ScopeRank {
+	scope := policyObject.API.Scope
+	switch {
+	case scope.UeID != nil && *scope.UeID != "":
+		return ScopeRankUe
+	case scope.SliceID != nil && scope.QosID != nil:
+		return ScopeRankSliceQos
+	case scope.SliceID != nil:
+		return ScopeRankSlice
+	default:
+		return ScopeRankCell
+	}
+}
+
+// HasPrecedenceV2 reports whether policy a wins over policy b. Policies are compared by
+// scope rank, then by explicit priority, then the most recently created one wins; the
+// policy ID is the last tie-breaker so the order never depends on map iteration.
+func HasPrecedenceV2(a *mho.PolicyData, b *mho.PolicyData) bool {
+	if rankA, rankB := GetScopeRankV2(a), GetScopeRankV2(b); rankA != rankB {
+		return rankA > rankB
+	}
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	return a.Key < b.Key
+}
+
+func SortByPrecedenceV2(policies []*mho.PolicyData) {
+	sort.SliceStable(policies, func(i, j int) bool {
+		return HasPrecedenceV2(policies[i], policies[j])
+	})
+}
+
+// GetCellPreferenceV2 returns the preference the policy assigns to the cell, if any.
+func GetCellPreferenceV2(policyObject *mho.PolicyData, queryCellId policyAPI.CellID) (policyAPI.PreferenceType, bool) {
+	for _, tspResource := range policyObject.API.TSPResources {
+		for _, cellId := range tspResource.CellIDList {
+			if IsSameCellV2(cellId, queryCellId) {
+				return tspResource.Preference, true
+			}
+		}
+	}
+	return "", false
+}
+
+// IsSameCellV2 compares the NCGIs or the ECGIs of the cells; an NCGI never equals an ECGI.
+func IsSameCellV2(a policyAPI.CellID, b policyAPI.CellID) bool {
+	return ((a.CID.NcI != nil && b.CID.NcI != nil && *a.CID.NcI == *b.CID.NcI) ||
+		(a.CID.EcI != nil && b.CID.EcI != nil && *a.CID.EcI == *b.CID.EcI)) &&
+		(a.PlmnID.Mcc == b.PlmnID.Mcc && a.PlmnID.Mnc == b.PlmnID.Mnc)
+}
+
+// IsSameSliceV2 compares the S-NSSAI and the PLMN of the slices, the SDs as IsSameSdV2 does.
+func IsSameSliceV2(a *policyAPI.SliceID, b *policyAPI.SliceID) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	return a.Sst == b.Sst && IsSameSdV2(a.SD, b.SD) &&
+		a.PlmnID.Mcc == b.PlmnID.Mcc && a.PlmnID.Mnc == b.PlmnID.Mnc
+}
+
+// IsSameSdV2 compares the hex SDs of slices case-insensitively; a missing SD equals an empty one.
+func IsSameSdV2(a *string, b *string) bool {
+	sdA, sdB := "", ""
+	if a != nil {
+		sdA = *a
+	}
+	if b != nil {
+		sdB = *b
+	}
+	return strings.EqualFold(sdA, sdB)
+}
+
+// IsSameQosV2 tells whether the QoS of the UE flow satisfies the QoS of the policy: every identifier set in the
+// policy has to be set to the same value in the flow. A policy QoS without any identifier matches nothing.
+func IsSameQosV2(policyQos *policyAPI.QosID, ueQos *policyAPI.QosID) bool {
+	if policyQos == nil || ueQos == nil || (policyQos.QcI == nil && policyQos.The5QI == nil) {
+		return false
+	}
+	if policyQos.QcI != nil && (ueQos.QcI == nil || *policyQos.QcI != *ueQos.QcI) {
+		return false
+	}
+	if policyQos.The5QI != nil && (ueQos.The5QI == nil || *policyQos.The5QI != *ueQos.The5QI) {
+		return false
+	}
+	return true
+}
+
+// PolicyConflict describes two enforced policies that can apply to the same UE
+// and assign different preferences to the same cell.
+type PolicyConflict struct {
+	Winner      *mho.PolicyData
+	Loser       *mho.PolicyData
+	CellID      policyAPI.CellID
+	WinnerPref  policyAPI.PreferenceType
+	LoserPref   policyAPI.PreferenceType
+	WinnerScope ScopeRank
+	LoserScope  ScopeRank
+}
+
+// GetConflictsV2 lists the enforced policies overlapping with the given one. Two policies
+// overlap when every scope dimension is either unset in one of them or equal in both,
+// so e.g. a per-UE and a per-slice policy may both apply to the same UE.
+func (m *PolicyManager) GetConflictsV2(policyObject *mho.PolicyData) []PolicyConflict {
+
+	keys := make([]string, 0, len(*m.policyMap))
+	for k := range *m.policyMap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	conflicts := make([]PolicyConflict, 0)
+	for _, key := range keys {
+		policy := (*m.policyMap)[key]
+		if key == policyObject.Key || !policy.IsEnforced || !isOverlappingScopeV2(policyObject.API.Scope, policy.API.Scope) {
+			continue
+		}
+		winner, loser := policyObject, policy
+		if !HasPrecedenceV2(winner, loser) {
+			winner, loser = loser, winner
+		}
+		for _, tspResource := range policyObject.API.TSPResources {
+			for _, cellId := range tspResource.CellIDList {
+				otherPreference, ok := GetCellPreferenceV2(policy, cellId)
+				if !ok || otherPreference == tspResource.Preference {
+					continue
+				}
+				winnerPref, loserPref := tspResource.Preference, otherPreference
+				if winner != policyObject {
+					winnerPref, loserPref = loserPref, winnerPref
+				}
+				conflicts = append(conflicts, PolicyConflict{
+					Winner:      winner,
+					Loser:       loser,
+					CellID:      cellId,
+					WinnerPref:  winnerPref,
+					LoserPref:   loserPref,
+					WinnerScope: GetScopeRankV2(winner),
+					LoserScope:  GetScopeRankV2(loser),
+				})
+			}
+		}
+	}
+	return conflicts
+}
+
+func isOverlappingScopeV2(a policyAPI.Scope, b policyAPI.Scope) bool {
+
+	if a.SliceID != nil && b.SliceID != nil &&
+		(a.SliceID.Sst != b.SliceID.Sst ||
+			(a.SliceID.SD != nil && b.SliceID.SD != nil && !IsSameSdV2(a.SliceID.SD, b.SliceID.SD)) ||
+			a.SliceID.PlmnID.Mcc != b.SliceID.PlmnID.Mcc ||
+			a.SliceID.PlmnID.Mnc != b.SliceID.PlmnID.Mnc) {
+		return false
+	}
+
+	if a.UeID != nil && b.UeID != nil && *a.UeID != "" && *b.UeID != "" && *a.UeID != *b.UeID {
+		return false
+	}
+
+	if a.QosID != nil && b.QosID != nil &&
+		((a.QosID.QcI != nil && b.QosID.QcI != nil && *a.QosID.QcI != *b.QosID.QcI) ||
+			(a.QosID.The5QI != nil && b.QosID.The5QI != nil && *a.QosID.The5QI != *b.QosID.The5QI)) {
+		return false
+	}
+
+	if a.CellID != nil && b.CellID != nil && !IsSameCellV2(*a.CellID, *b.CellID) {
+		return false
+	}
+
+	return true
+}
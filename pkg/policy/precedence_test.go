@@ -0,0 +1,244 @@
+// SPDX-FileCopyrightText: 2019-present Open Networking Foundation <info@opennetworking.org>
+// SPDX-FileCopyrightText: 2019-present Rimedo Labs
+//
+// SPDX-License-Identifier: Apache-2.0
+// Created by RIMEDO-Labs team
+package policy
+
+import (
+	"testing"
+	"time"
+
+	policyAPI "github.com/onosproject/onos-a1-dm/go/policy_schemas/traffic_steering_preference/v2"
+	"github.com/onosproject/rimedo-ts/pkg/mho"
+)
+
+func newCellID(nci int64) policyAPI.CellID {
+	return policyAPI.CellID{
+		CID:    policyAPI.CID{NcI: &nci},
+		PlmnID: policyAPI.PlmnID{Mcc: "314", Mnc: "628"},
+	}
+}
+
+func newSliceID(sst int64, sd string) *policyAPI.SliceID {
+	return &policyAPI.SliceID{
+		PlmnID: policyAPI.PlmnID{Mcc: "314", Mnc: "628"},
+		SD:     &sd,
+		Sst:    sst,
+	}
+}
+
+func newPolicy(key string, scope policyAPI.Scope, preference policyAPI.PreferenceType, cellIDs ...policyAPI.CellID) *mho.PolicyData {
+	return &mho.PolicyData{
+		Key: key,
+		API: &policyAPI.API{
+			Scope: scope,
+			TSPResources: []policyAPI.TSPResource{
+				{CellIDList: cellIDs, Preference: preference},
+			},
+		},
+		IsEnforced: true,
+	}
+}
+
+func TestGetScopeRankV2(t *testing.T) {
+	ueID := "0000000000000001"
+	qci := int64(9)
+	tests := []struct {
+		scope policyAPI.Scope
+		rank  ScopeRank
+	}{
+		{scope: policyAPI.Scope{UeID: &ueID, SliceID: newSliceID(1, "000001")}, rank: ScopeRankUe},
+		{scope: policyAPI.Scope{SliceID: newSliceID(1, "000001"), QosID: &policyAPI.QosID{QcI: &qci}}, rank: ScopeRankSliceQos},
+		{scope: policyAPI.Scope{SliceID: newSliceID(1, "000001")}, rank: ScopeRankSlice},
+		{scope: policyAPI.Scope{CellID: &[]policyAPI.CellID{newCellID(1)}[0]}, rank: ScopeRankCell},
+	}
+	for _, test := range tests {
+		if rank := GetScopeRankV2(newPolicy("policy", test.scope, policyAPI.Prefer)); rank != test.rank {
+			t.Errorf("scope %+v ranked %v, %v expected", test.scope, rank, test.rank)
+		}
+	}
+}
+
+func TestSortByPrecedenceV2(t *testing.T) {
+	ueID := "0000000000000001"
+	now := time.Now()
+
+	ue := newPolicy("ue", policyAPI.Scope{UeID: &ueID}, policyAPI.Prefer)
+	slice := newPolicy("slice", policyAPI.Scope{SliceID: newSliceID(1, "000001")}, policyAPI.Prefer)
+	slicePriority := newPolicy("slice-priority", policyAPI.Scope{SliceID: newSliceID(1, "000001")}, policyAPI.Prefer)
+	slicePriority.Priority = 1
+	sliceNewer := newPolicy("slice-newer", policyAPI.Scope{SliceID: newSliceID(1, "000001")}, policyAPI.Prefer)
+	sliceNewer.CreatedAt = now
+	sliceOlder := newPolicy("slice-older", policyAPI.Scope{SliceID: newSliceID(1, "000001")}, policyAPI.Prefer)
+	sliceOlder.CreatedAt = now.Add(-time.Minute)
+	sliceTie := newPolicy("slice-tie", policyAPI.Scope{SliceID: newSliceID(1, "000001")}, policyAPI.Prefer)
+	sliceTie.CreatedAt = now
+
+	policies := []*mho.PolicyData{sliceOlder, slice, sliceTie, ue, sliceNewer, slicePriority}
+	SortByPrecedenceV2(policies)
+
+	expected := []string{"ue", "slice-priority", "slice-newer", "slice-tie", "slice-older", "slice"}
+	for i, policy := range policies {
+		if policy.Key != expected[i] {
+			t.Fatalf("policy %v at position %v, %v expected (order %v)", policy.Key, i, expected[i], keys(policies))
+		}
+	}
+}
+
+func TestIsSameSdV2(t *testing.T) {
+	sd, upperSd, otherSd, emptySd := "00abcd", "00ABCD", "00abce", ""
+	tests := []struct {
+		a    *string
+		b    *string
+		same bool
+	}{
+		{a: &sd, b: &sd, same: true},
+		{a: &sd, b: &upperSd, same: true},
+		{a: &sd, b: &otherSd, same: false},
+		{a: nil, b: &emptySd, same: true},
+		{a: nil, b: nil, same: true},
+		{a: &sd, b: nil, same: false},
+	}
+	for _, test := range tests {
+		if same := IsSameSdV2(test.a, test.b); same != test.same {
+			t.Errorf("SDs %v and %v compared as same %v, %v expected", deref(test.a), deref(test.b), same, test.same)
+		}
+	}
+}
+
+func TestIsOverlappingScopeV2(t *testing.T) {
+	ueA, ueB := "0000000000000001", "0000000000000002"
+	qci, otherQci := int64(9), int64(7)
+	tests := []struct {
+		name        string
+		a           policyAPI.Scope
+		b           policyAPI.Scope
+		overlapping bool
+	}{
+		{
+			name:        "same slice with the SD in another case",
+			a:           policyAPI.Scope{SliceID: newSliceID(1, "00abcd")},
+			b:           policyAPI.Scope{SliceID: newSliceID(1, "00ABCD")},
+			overlapping: true,
+		},
+		{
+			name: "other SD",
+			a:    policyAPI.Scope{SliceID: newSliceID(1, "00abcd")},
+			b:    policyAPI.Scope{SliceID: newSliceID(1, "00abce")},
+		},
+		{
+			name: "other SST",
+			a:    policyAPI.Scope{SliceID: newSliceID(1, "00abcd")},
+			b:    policyAPI.Scope{SliceID: newSliceID(2, "00abcd")},
+		},
+		{
+			name:        "UE and slice",
+			a:           policyAPI.Scope{UeID: &ueA},
+			b:           policyAPI.Scope{SliceID: newSliceID(1, "00abcd")},
+			overlapping: true,
+		},
+		{
+			name: "other UEs",
+			a:    policyAPI.Scope{UeID: &ueA},
+			b:    policyAPI.Scope{UeID: &ueB},
+		},
+		{
+			name: "other QoS",
+			a:    policyAPI.Scope{SliceID: newSliceID(1, "00abcd"), QosID: &policyAPI.QosID{QcI: &qci}},
+			b:    policyAPI.Scope{SliceID: newSliceID(1, "00abcd"), QosID: &policyAPI.QosID{QcI: &otherQci}},
+		},
+	}
+	for _, test := range tests {
+		if overlapping := isOverlappingScopeV2(test.a, test.b); overlapping != test.overlapping {
+			t.Errorf("%v: overlapping %v, %v expected", test.name, overlapping, test.overlapping)
+		}
+	}
+}
+
+func TestGetConflictsV2(t *testing.T) {
+	ueID := "0000000000000001"
+	cell1, cell2 := newCellID(1), newCellID(2)
+
+	ue := newPolicy("ue", policyAPI.Scope{UeID: &ueID}, policyAPI.Forbid, cell1)
+	slice := newPolicy("slice", policyAPI.Scope{SliceID: newSliceID(1, "00abcd")}, policyAPI.Shall, cell1, cell2)
+	otherSlice := newPolicy("other-slice", policyAPI.Scope{SliceID: newSliceID(2, "00abcd")}, policyAPI.Avoid, cell1)
+	disabled := newPolicy("disabled", policyAPI.Scope{SliceID: newSliceID(1, "00ABCD")}, policyAPI.Avoid, cell2)
+	disabled.IsEnforced = false
+	policyMap := map[string]*mho.PolicyData{
+		ue.Key:         ue,
+		slice.Key:      slice,
+		otherSlice.Key: otherSlice,
+		disabled.Key:   disabled,
+	}
+	m := NewPolicyManager(&policyMap, "")
+
+	conflicts := m.GetConflictsV2(slice)
+	if len(conflicts) != 1 {
+		t.Fatalf("%v conflicts of the slice policy, 1 expected: %+v", len(conflicts), conflicts)
+	}
+	conflict := conflicts[0]
+	if conflict.Winner != ue || conflict.Loser != slice || !IsSameCellV2(conflict.CellID, cell1) {
+		t.Errorf("conflict %v over %v for cell %v, ue over slice for cell 1 expected", conflict.Winner.Key, conflict.Loser.Key, *conflict.CellID.CID.NcI)
+	}
+	if conflict.WinnerPref != policyAPI.Forbid || conflict.LoserPref != policyAPI.Shall {
+		t.Errorf("conflict %v over %v, FORBID over SHALL expected", conflict.WinnerPref, conflict.LoserPref)
+	}
+	if conflict.WinnerScope != ScopeRankUe || conflict.LoserScope != ScopeRankSlice {
+		t.Errorf("conflict of scope %v over %v, UE over SLICE expected", conflict.WinnerScope, conflict.LoserScope)
+	}
+
+	if conflicts := m.GetConflictsV2(otherSlice); len(conflicts) != 1 || conflicts[0].Winner != ue {
+		t.Errorf("conflicts of the other slice policy %+v, one with the UE policy expected", conflicts)
+	}
+}
+
+func TestGetConstraintsV2(t *testing.T) {
+	ueID := "0000000000000001"
+	cell1, cell2, cell3 := newCellID(1), newCellID(2), newCellID(3)
+	cellIDs := []policyAPI.CellID{cell1, cell2, cell3}
+
+	ue := newPolicy("ue", policyAPI.Scope{UeID: &ueID}, policyAPI.Forbid, cell1)
+	slice := newPolicy("slice", policyAPI.Scope{SliceID: newSliceID(1, "00abcd")}, policyAPI.Shall, cell1, cell2)
+	matching := []*mho.PolicyData{slice, ue}
+	SortByPrecedenceV2(matching)
+
+	allowed, constraining, feasible := GetConstraintsV2(matching, cellIDs)
+	if !feasible {
+		t.Fatal("no cell allowed, cell 2 expected")
+	}
+	if allowed[0] || !allowed[1] || allowed[2] {
+		t.Errorf("cells allowed %v, only cell 2 expected", allowed)
+	}
+	if len(constraining) != 2 || constraining[0] != ue || constraining[1] != slice {
+		t.Errorf("constraining policies %v, ue and slice expected", keys(constraining))
+	}
+
+	// the UE policy overrides the only SHALL cell, which leaves none
+	slice.API.TSPResources[0].CellIDList = []policyAPI.CellID{cell1}
+	allowed, _, feasible = GetConstraintsV2(matching, cellIDs)
+	if !feasible || allowed[0] || !allowed[1] || !allowed[2] {
+		t.Errorf("cells allowed %v, the SHALL overridden by FORBID should leave cells 2 and 3", allowed)
+	}
+
+	ue.API.TSPResources[0].CellIDList = []policyAPI.CellID{cell2}
+	allowed, _, feasible = GetConstraintsV2(matching, []policyAPI.CellID{cell2, cell3})
+	if feasible || allowed[0] || allowed[1] {
+		t.Errorf("cells allowed %v, none expected without the SHALL cell 1", allowed)
+	}
+}
+
+func keys(policies []*mho.PolicyData) []string {
+	output := make([]string, len(policies))
+	for i, policy := range policies {
+		output[i] = policy.Key
+	}
+	return output
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return "<nil>"
+	}
+	return *s
+}
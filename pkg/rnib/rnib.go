@@ -83,6 +83,23 @@ func (c *Client) GetE2CellFilter() *topoapi.Filters {
 	return cellEntityFilter
 }
 
+// WatchE2Cells watches the E2 cells of the topology, the existing ones are sent first with the NONE event type
+func (c *Client) WatchE2Cells(ctx context.Context, ch chan topoapi.Event) error {
+	return c.client.Watch(ctx, ch, toposdk.WithWatchFilters(c.GetE2CellFilter()))
+}
+
+// NewCell returns the CGI and the cell type of the E2 cell entity
+func NewCell(object *topoapi.Object) (Cell, error) {
+	cellObject := &topoapi.E2Cell{}
+	if err := object.GetAspect(cellObject); err != nil {
+		return Cell{}, err
+	}
+	return Cell{
+		CGI:      cellObject.CellObjectID,
+		CellType: cellObject.CellType,
+	}, nil
+}
+
 func (c *Client) GetCellTypes(ctx context.Context) (map[string]Cell, error) {
 	output := make(map[string]Cell)
 
@@ -148,3 +165,12 @@ func (c *Client) GetE2NodeAspects(ctx context.Context, nodeID topoapi.ID) (*topo
 	return e2Node, err
 
 }
+
+// GetE2NodeLabels returns the labels of the E2 node in the topology.
+func (c *Client) GetE2NodeLabels(ctx context.Context, nodeID topoapi.ID) (map[string]string, error) {
+	object, err := c.client.Get(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	return object.GetLabels(), nil
+}
@@ -7,23 +7,25 @@ package sdran
 
 import (
 	"context"
-	"strconv"
+	"reflect"
 	"sync"
+	"time"
 
 	policyAPI "github.com/onosproject/onos-a1-dm/go/policy_schemas/traffic_steering_preference/v2"
-	e2tAPI "github.com/onosproject/onos-api/go/onos/e2t/e2"
-	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
-	"github.com/onosproject/onos-e2-sm/servicemodels/e2sm_mho_go/pdubuilder"
 	e2sm_v2_ies "github.com/onosproject/onos-e2-sm/servicemodels/e2sm_mho_go/v2/e2sm-v2-ies"
 	"github.com/onosproject/onos-lib-go/pkg/logging"
 	"github.com/onosproject/onos-lib-go/pkg/logging/service"
 	"github.com/onosproject/onos-lib-go/pkg/northbound"
-	control "github.com/onosproject/onos-mho/pkg/mho"
 	"github.com/onosproject/onos-mho/pkg/store"
+	"github.com/onosproject/onos-ric-sdk-go/pkg/config/event"
+	"github.com/onosproject/rimedo-ts/pkg/capture"
+	appConfig "github.com/onosproject/rimedo-ts/pkg/config"
+	"github.com/onosproject/rimedo-ts/pkg/handover"
 	"github.com/onosproject/rimedo-ts/pkg/mho"
 	"github.com/onosproject/rimedo-ts/pkg/policy"
 	"github.com/onosproject/rimedo-ts/pkg/rnib"
 	"github.com/onosproject/rimedo-ts/pkg/southbound/e2"
+	"github.com/onosproject/rimedo-ts/pkg/steering"
 )
 
 var log = logging.GetLogger("rimedo-ts", "sdran", "manager")
@@ -37,6 +39,7 @@ type Config struct {
 	SMName             string
 	SMVersion          string
 	TSPolicySchemePath string
+	ConfigPath         string
 }
 
 func NewManager(config Config, flag bool) *Manager {
@@ -47,8 +50,18 @@ func NewManager(config Config, flag bool) *Manager {
 
 	policyMap := make(map[string]*mho.PolicyData)
 
-	indCh := make(chan *mho.E2NodeIndication)
-	ctrlReqChs := make(map[string]chan *e2api.ControlMessage)
+	tsConfig, err := appConfig.NewConfig(config.ConfigPath)
+	if err != nil {
+		log.Warnf("Couldn't load xApp configuration from %v, using defaults: %v", config.ConfigPath, err)
+		tsConfig = appConfig.NewDefaultConfig()
+	}
+
+	// the sizes of the indication queues are only read at startup
+	indications := tsConfig.GetIndications()
+	if indications.InputQueueSize <= 0 {
+		indications.InputQueueSize = appConfig.DefaultIndications().InputQueueSize
+	}
+	indCh := make(chan *mho.E2NodeIndication, indications.InputQueueSize)
 
 	options := e2.Options{
 		AppID:       config.AppID,
@@ -60,22 +73,25 @@ func NewManager(config Config, flag bool) *Manager {
 		SMVersion:   config.SMVersion,
 	}
 
-	e2Manager, err := e2.NewManager(options, indCh, ctrlReqChs)
+	e2Manager, err := e2.NewManager(options, indCh)
 	if err != nil {
 		log.Warn(err)
 	}
 
 	manager := &Manager{
 		e2Manager:       e2Manager,
-		mhoCtrl:         mho.NewController(indCh, ueStore, cellStore, onosPolicyStore, policyMap, flag),
-		policyManager:   policy.NewPolicyManager(&policyMap),
+		mhoCtrl:         mho.NewController(indCh, ueStore, cellStore, onosPolicyStore, policyMap, flag, indications.Workers, indications.WorkerQueueSize),
+		policyManager:   policy.NewPolicyManager(&policyMap, config.TSPolicySchemePath),
 		ueStore:         ueStore,
 		cellStore:       cellStore,
 		onosPolicyStore: onosPolicyStore,
-		ctrlReqChs:      ctrlReqChs,
 		services:        []service.Service{},
 		mutex:           sync.RWMutex{},
+		config:          tsConfig,
+		hoTrigger:       handover.NewTrigger(0, 0),
+		hoLimiter:       handover.NewLimiter(0, 0, 0, 0),
 	}
+	manager.applyConfig()
 	return manager
 }
 
@@ -86,9 +102,15 @@ type Manager struct {
 	ueStore         store.Store
 	cellStore       store.Store
 	onosPolicyStore store.Store
-	ctrlReqChs      map[string]chan *e2api.ControlMessage
 	services        []service.Service
 	mutex           sync.RWMutex
+	config          appConfig.Config
+	hoTrigger       *handover.Trigger
+	hoLimiter       *handover.Limiter
+	algorithm       steering.SteeringAlgorithm
+	algorithmConfig *appConfig.Steering
+	algorithmMu     sync.RWMutex
+	recorder        *capture.Recorder
 }
 
 func (m *Manager) Run(flag *bool) {
@@ -99,17 +121,174 @@ func (m *Manager) Run(flag *bool) {
 
 func (m *Manager) start(flag *bool) error {
 	_ = m.startNorthboundServer()
+	m.e2Manager.SetE2NodeRemovedHandler(func(nodeID string) {
+		removedUes, removedCGIs := m.mhoCtrl.RemoveE2Node(context.Background(), nodeID)
+		for _, ueID := range removedUes {
+			m.hoTrigger.Forget(ueID)
+			m.hoLimiter.Forget(ueID)
+		}
+		for _, cgi := range removedCGIs {
+			m.hoTrigger.ForgetCell(cgi)
+			m.hoLimiter.ForgetCell(cgi)
+		}
+	})
+	m.mhoCtrl.SetUeDetachedHandler(func(ueID string) {
+		m.hoTrigger.Forget(ueID)
+		m.hoLimiter.Forget(ueID)
+	})
 	err := m.e2Manager.Start()
 	if err != nil {
 		log.Warn(err)
 		return err
 	}
 
+	m.startCapture(context.Background())
+
 	go m.mhoCtrl.Run(context.Background(), flag)
 
+	go m.watchConfig(context.Background())
+
 	return nil
 }
 
+// startCapture starts recording the indications and replaying the capture file, if configured
+func (m *Manager) startCapture(ctx context.Context) {
+	captureConfig := m.config.GetCapture()
+	if captureConfig.Record != "" && captureConfig.Replay != "" && capture.SameFile(captureConfig.Record, captureConfig.Replay) {
+		log.Warnf("Not recording indications to %v, the capture replayed", captureConfig.Record)
+	} else if captureConfig.Record != "" {
+		recorder, err := capture.NewRecorder(captureConfig.Record)
+		if err != nil {
+			log.Warnf("Couldn't record indications to %v: %v", captureConfig.Record, err)
+		} else {
+			log.Infof("Recording indications to %v", captureConfig.Record)
+			m.recorder = recorder
+			m.mhoCtrl.SetIndicationRecorder(func(indication *mho.E2NodeIndication) {
+				if err := recorder.Record(indication); err != nil {
+					log.Warn(err)
+				}
+			})
+			go recorder.Run(ctx)
+		}
+	}
+	if captureConfig.Replay != "" {
+		go func() {
+			log.Infof("Replaying indications from %v at speed %v", captureConfig.Replay, captureConfig.ReplaySpeed)
+			sent, err := capture.Replay(ctx, captureConfig.Replay, m.mhoCtrl.IndChan, captureConfig.ReplaySpeed)
+			if err != nil {
+				log.Warnf("Replay of %v stopped after %v indications: %v", captureConfig.Replay, sent, err)
+				return
+			}
+			log.Infof("Replayed %v indications from %v", sent, captureConfig.Replay)
+		}()
+	}
+}
+
+// Close stops recording the indications and closes the capture file
+func (m *Manager) Close() {
+	if m.recorder == nil {
+		return
+	}
+	m.mhoCtrl.SetIndicationRecorder(nil)
+	if err := m.recorder.Close(); err != nil {
+		log.Warn(err)
+	}
+}
+
+func (m *Manager) watchConfig(ctx context.Context) {
+	ch := make(chan event.Event)
+	err := m.config.Watch(ctx, ch)
+	if err != nil {
+		log.Warn(err)
+		return
+	}
+	for configEvent := range ch {
+		log.Infof("CONFIG MESSAGE: %v changed, reloading configuration\n", configEvent.Key)
+		m.applyConfig()
+	}
+}
+
+func (m *Manager) applyConfig() {
+	m.policyManager.SetScoringV2(m.config.GetScoring(), m.config.GetSliceScoring())
+	hoConfig := m.config.GetHandover()
+	m.hoTrigger.SetParameters(hoConfig.Hysteresis, time.Duration(hoConfig.TimeToTrigger)*time.Millisecond)
+	m.mhoCtrl.SetHandoverTimeout(time.Duration(hoConfig.ConfirmationTimeout) * time.Millisecond)
+	controlConfig := m.config.GetControl()
+	m.e2Manager.SetControlRetry(e2.ControlRetry{
+		MaxRetries:     controlConfig.MaxRetries,
+		InitialBackoff: time.Duration(controlConfig.InitialBackoff) * time.Millisecond,
+		MaxBackoff:     time.Duration(controlConfig.MaxBackoff) * time.Millisecond,
+	})
+	triggers := m.config.GetTriggers()
+	m.e2Manager.SetTriggers(triggers.GetTriggers)
+	if err := m.mhoCtrl.SetIndicationOverflow(m.config.GetIndications().Overflow); err != nil {
+		log.Warnf("%v, using %v", err, mho.OverflowBlock)
+		_ = m.mhoCtrl.SetIndicationOverflow(mho.OverflowBlock)
+	}
+	m.applyPolicyPriorities(context.Background(), m.config.GetPolicies().Priorities)
+	rateLimit := m.config.GetRateLimit()
+	m.hoLimiter.SetParameters(rateLimit.UePerMinute, rateLimit.UeBurst, rateLimit.CellPerMinute, rateLimit.CellBurst)
+	m.applySteeringConfig(m.config.GetSteering())
+	slices := m.config.GetSlices()
+	m.mhoCtrl.SetSliceResolver(func(ueData *mho.UeData) *policyAPI.SliceID {
+		slice := slices.GetSlice(ueData.UeID, ueData.E2NodeID, ueData.CGIString)
+		if slice == nil {
+			return nil
+		}
+		sd := slice.SD
+		return &policyAPI.SliceID{
+			SD:  &sd,
+			Sst: slice.Sst,
+			PlmnID: policyAPI.PlmnID{
+				Mcc: slice.Mcc,
+				Mnc: slice.Mnc,
+			},
+		}
+	})
+}
+
+// applyPolicyPriorities sets the configured priorities of the existing policies, new policies get theirs when created
+func (m *Manager) applyPolicyPriorities(ctx context.Context, priorities map[string]int) {
+	for key, policyObject := range m.GetPolicies(ctx) {
+		policyObject := policyObject
+		if priority := priorities[key]; policyObject.Priority != priority {
+			log.Infof("POLICY MESSAGE: Policy [ID:%v] priority changed from %v to %v\n", key, policyObject.Priority, priority)
+			policyObject.Priority = priority
+			m.mhoCtrl.SetPolicy(ctx, key, &policyObject)
+		}
+	}
+}
+
+// applySteeringConfig creates the configured steering algorithm, the running one is kept if its configuration didn't change
+func (m *Manager) applySteeringConfig(steeringConfig appConfig.Steering) {
+	m.algorithmMu.Lock()
+	defer m.algorithmMu.Unlock()
+	if m.algorithmConfig != nil && m.algorithmConfig.Algorithm == steeringConfig.Algorithm &&
+		reflect.DeepEqual(m.algorithmConfig.Parameters, steeringConfig.Parameters) {
+		return
+	}
+	name := steeringConfig.Algorithm
+	if name == "" {
+		name = steering.DefaultAlgorithm
+	}
+	algorithm, err := steering.NewAlgorithm(name, m.policyManager, steeringConfig.Parameters)
+	if err != nil {
+		log.Warnf("Couldn't create steering algorithm %v, using %v: %v", name, steering.DefaultAlgorithm, err)
+		algorithm, err = steering.NewAlgorithm(steering.DefaultAlgorithm, m.policyManager, nil)
+		if err != nil {
+			log.Error(err)
+			return
+		}
+	}
+	log.Infof("Steering algorithm: %v", algorithm.Name())
+	m.algorithm = algorithm
+	m.algorithmConfig = &steeringConfig
+}
+
+func (m *Manager) GetConfig() appConfig.Config {
+	return m.config
+}
+
 func (m *Manager) startNorthboundServer() error {
 
 	s := northbound.NewServer(northbound.NewServerCfg(
@@ -144,13 +323,12 @@ func (m *Manager) AddService(service service.Service) {
 
 func (m *Manager) GetUEs(ctx context.Context) map[string]mho.UeData {
 	output := make(map[string]mho.UeData)
-	chEntries := make(chan *store.Entry, 1024)
-	err := m.ueStore.Entries(ctx, chEntries)
+	entries, err := mho.GetStoreEntries(ctx, m.ueStore)
 	if err != nil {
 		log.Warn(err)
 		return output
 	}
-	for entry := range chEntries {
+	for _, entry := range entries {
 		ueData := entry.Value.(mho.UeData)
 		output[ueData.UeID] = ueData
 	}
@@ -159,13 +337,12 @@ func (m *Manager) GetUEs(ctx context.Context) map[string]mho.UeData {
 
 func (m *Manager) GetCells(ctx context.Context) map[string]mho.CellData {
 	output := make(map[string]mho.CellData)
-	chEntries := make(chan *store.Entry, 1024)
-	err := m.cellStore.Entries(ctx, chEntries)
+	entries, err := mho.GetStoreEntries(ctx, m.cellStore)
 	if err != nil {
 		log.Warn(err)
 		return output
 	}
-	for entry := range chEntries {
+	for _, entry := range entries {
 		cellData := entry.Value.(mho.CellData)
 		output[cellData.CGIString] = cellData
 	}
@@ -174,13 +351,12 @@ func (m *Manager) GetCells(ctx context.Context) map[string]mho.CellData {
 
 func (m *Manager) GetPolicies(ctx context.Context) map[string]mho.PolicyData {
 	output := make(map[string]mho.PolicyData)
-	chEntries := make(chan *store.Entry, 1024)
-	err := m.onosPolicyStore.Entries(ctx, chEntries)
+	entries, err := mho.GetStoreEntries(ctx, m.onosPolicyStore)
 	if err != nil {
 		log.Warn(err)
 		return output
 	}
-	for entry := range chEntries {
+	for _, entry := range entries {
 		policyData := entry.Value.(mho.PolicyData)
 		output[policyData.Key] = policyData
 	}
@@ -213,6 +389,12 @@ func (m *Manager) AttachUe(ctx context.Context, ue *mho.UeData, CGI string, cgiO
 
 }
 
+func (m *Manager) SetUeChangedHandler(handler func(ueID string)) {
+
+	m.mhoCtrl.SetUeChangedHandler(handler)
+
+}
+
 func (m *Manager) GetUe(ctx context.Context, ueID string) *mho.UeData {
 
 	return m.mhoCtrl.GetUe(ctx, ueID)
@@ -227,7 +409,7 @@ func (m *Manager) SetUe(ctx context.Context, ueData *mho.UeData) {
 
 func (m *Manager) CreatePolicy(ctx context.Context, key string, policy *policyAPI.API) *mho.PolicyData {
 
-	return m.mhoCtrl.CreatePolicy(ctx, key, policy)
+	return m.mhoCtrl.CreatePolicy(ctx, key, policy, m.config.GetPolicies().Priorities[key])
 
 }
 
@@ -246,6 +428,7 @@ func (m *Manager) SetPolicy(ctx context.Context, key string, policy *mho.PolicyD
 func (m *Manager) DeletePolicy(ctx context.Context, key string) {
 
 	m.mhoCtrl.DeletePolicy(ctx, key)
+	m.policyManager.ForgetPolicyV2(key)
 
 }
 
@@ -253,14 +436,53 @@ func (m *Manager) GetPolicyStore() *store.Store {
 	return m.mhoCtrl.GetPolicyStore()
 }
 
-func (m *Manager) GetControlChannelsMap(ctx context.Context) map[string]chan *e2api.ControlMessage {
-	return m.ctrlReqChs
+func (m *Manager) GetNodeRegistry() *e2.NodeRegistry {
+	return m.e2Manager.GetNodeRegistry()
 }
 
 func (m *Manager) GetPolicyManager() *policy.PolicyManager {
 	return m.policyManager
 }
 
+func (m *Manager) GetHandoverTrigger() *handover.Trigger {
+	return m.hoTrigger
+}
+
+func (m *Manager) GetHandoverStats() mho.HandoverStats {
+	return m.mhoCtrl.GetHandoverStats()
+}
+
+func (m *Manager) GetControlStats() e2.ControlStats {
+	return m.e2Manager.GetControlStats()
+}
+
+func (m *Manager) GetSubscriptionHealth() []e2.SubscriptionHealth {
+	return m.e2Manager.GetSubscriptionHealth()
+}
+
+func (m *Manager) GetTriggers() map[string]appConfig.Triggers {
+	return m.e2Manager.GetTriggers()
+}
+
+func (m *Manager) GetCapabilities() map[string]e2.Capabilities {
+	return m.e2Manager.GetCapabilities()
+}
+
+// GetIndicationStats returns the counters and the queue lengths of the indication processing
+func (m *Manager) GetIndicationStats() mho.IndicationStats {
+	return m.mhoCtrl.GetIndicationStats()
+}
+
+func (m *Manager) GetHandoverLimiter() *handover.Limiter {
+	return m.hoLimiter
+}
+
+func (m *Manager) GetSteeringAlgorithm() steering.SteeringAlgorithm {
+	m.algorithmMu.RLock()
+	defer m.algorithmMu.RUnlock()
+	return m.algorithm
+}
+
 func (m *Manager) SwitchUeBetweenCells(ctx context.Context, ueID string, targetCellCGI string) {
 
 	m.mutex.Lock()
@@ -271,62 +493,74 @@ func (m *Manager) SwitchUeBetweenCells(ctx context.Context, ueID string, targetC
 
 	if shouldBeSwitched(chosenUe, targetCellCGI) {
 
+		if chosenUe.UeIdentity == nil {
+			log.Errorf("SendHORequest() UE [ID:%v] has no UE identity received in an indication, handover not requested", chosenUe.UeID)
+			return
+		}
+
+		if !m.e2Manager.GetNodeRegistry().Contains(chosenUe.E2NodeID) {
+			log.Warnf("CONTROL MESSAGE: UE [ID:%v] not switched - E2 node %v not connected\n", chosenUe.UeID, chosenUe.E2NodeID)
+			return
+		}
+
 		targetCell := m.GetCell(ctx, targetCellCGI)
 		servingCell := m.GetCell(ctx, chosenUe.CGIString)
+		if targetCell == nil || servingCell == nil {
+			log.Warnf("CONTROL MESSAGE: UE [ID:%v] not switched - CELL [CGI:%v] or [CGI:%v] no longer known\n", chosenUe.UeID, chosenUe.CGIString, targetCellCGI)
+			return
+		}
+
+		reservation, allowed, reason := m.hoLimiter.Reserve(chosenUe.UeID, targetCellCGI, time.Now())
+		if !allowed {
+			log.Infof("CONTROL MESSAGE: UE [ID:%v] switch to CELL [CGI:%v] suppressed - %v\n", chosenUe.UeID, targetCellCGI, reason)
+			return
+		}
+
+		handedOverUe, started, ok := m.mhoCtrl.StartHandover(ctx, ueID, targetCellCGI, targetCell.CGI)
+		if !ok {
+			log.Warnf("CONTROL MESSAGE: UE [ID:%v] not switched - UE no longer known\n", ueID)
+			m.hoLimiter.Refund(reservation)
+			return
+		}
 
 		targetCell.CumulativeHandoversOut++
 		servingCell.CumulativeHandoversIn++
 
-		chosenUe.Idle = false
-		m.AttachUe(ctx, &chosenUe, targetCellCGI, targetCell.CGI)
-
 		m.SetCell(ctx, targetCell)
 		m.SetCell(ctx, servingCell)
 
-		controlChannel := m.ctrlReqChs[chosenUe.E2NodeID]
-
-		controlHandler := &control.E2SmMhoControlHandler{
-			NodeID:            chosenUe.E2NodeID,
-			ControlAckRequest: e2tAPI.ControlAckRequest_NO_ACK,
-		}
-
-		ueIDnum, err := strconv.Atoi(chosenUe.UeID)
-		if err != nil {
-			log.Errorf("SendHORequest() failed to convert string %v to decimal number - assumption is not satisfied (UEID is a decimal number): %v", chosenUe.UeID, err)
+		controlRequest := &e2.ControlRequest{
+			UeID: handedOverUe.UeID,
+			Handover: e2.Handover{
+				UeIdentity: handedOverUe.UeIdentity,
+				ServingCGI: handedOverUe.Handover.SourceCGI,
+				TargetCGI:  targetCell.CGI,
+			},
+			Done: func(result e2.ControlResult) {
+				m.handleControlResult(ctx, started, reservation, result)
+			},
 		}
-
-		ueIdentity, err := pdubuilder.CreateUeIDGNb(int64(ueIDnum), []byte{0xAA, 0xBB, 0xCC}, []byte{0xDD}, []byte{0xCC, 0xC0}, []byte{0xFC})
-		if err != nil {
-			log.Errorf("SendHORequest() Failed to create UEID: %v", err)
+		if err := m.e2Manager.SendControl(handedOverUe.E2NodeID, controlRequest); err != nil {
+			log.Warnf("CONTROL MESSAGE: UE [ID:%v] not switched - %v\n", handedOverUe.UeID, err)
+			m.mhoCtrl.FailHandover(ctx, handedOverUe.UeID, started, "control request not queued")
+			m.hoLimiter.Refund(reservation)
+			return
 		}
+		log.Infof("CONTROL MESSAGE: UE [ID:%v, 5QI:%v] switched between CELLs [CGI:%v -> CGI:%v]\n", handedOverUe.UeID, handedOverUe.FiveQi, handedOverUe.Handover.SourceCGIString, targetCell.CGIString)
 
-		servingPlmnIDBytes := servingCell.CGI.GetNRCgi().GetPLmnidentity().GetValue()
-		servingNCI := servingCell.CGI.GetNRCgi().GetNRcellIdentity().GetValue().GetValue()
-		servingNCILen := servingCell.CGI.GetNRCgi().GetNRcellIdentity().GetValue().GetLen()
-
-		go func() {
-			if controlHandler.ControlHeader, err = controlHandler.CreateMhoControlHeader(servingNCI, servingNCILen, 1, servingPlmnIDBytes); err == nil {
-
-				if controlHandler.ControlMessage, err = controlHandler.CreateMhoControlMessage(servingCell.CGI, ueIdentity, targetCell.CGI); err == nil {
-
-					if controlRequest, err := controlHandler.CreateMhoControlRequest(); err == nil {
-
-						controlChannel <- controlRequest
-						log.Infof("CONTROL MESSAGE: UE [ID:%v, 5QI:%v] switched between CELLs [CGI:%v -> CGI:%v]\n", chosenUe.UeID, chosenUe.FiveQi, servingCell.CGIString, targetCell.CGIString)
+	}
 
-					} else {
-						log.Warn("Control request problem!", err)
-					}
-				} else {
-					log.Warn("Control message problem!", err)
-				}
-			} else {
-				log.Warn("Control header problem!", err)
-			}
-		}()
+}
 
+// handleControlResult records the acknowledgement of the handover, or rolls it back and refunds its rate limit
+// tokens if the control request failed.
+func (m *Manager) handleControlResult(ctx context.Context, started time.Time, reservation handover.Reservation, result e2.ControlResult) {
+	if result.Success {
+		m.mhoCtrl.AcknowledgeHandover(ctx, result.UeID, started)
+		return
 	}
-
+	m.mhoCtrl.FailHandover(ctx, result.UeID, started, "control request failed: "+result.Cause)
+	m.hoLimiter.Refund(reservation)
 }
 
 func shouldBeSwitched(ue mho.UeData, cgi string) bool {
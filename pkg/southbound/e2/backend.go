@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2019-present Open Networking Foundation <info@opennetworking.org>
+// SPDX-FileCopyrightText: 2019-present Rimedo Labs
+//
+// SPDX-License-Identifier: Apache-2.0
+// Created by RIMEDO-Labs team
+
+package e2
+
+import (
+	e2tapi "github.com/onosproject/onos-api/go/onos/e2t/e2"
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+	e2sm_v2_ies "github.com/onosproject/onos-e2-sm/servicemodels/e2sm_mho_go/v2/e2sm-v2-ies"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	control "github.com/onosproject/onos-mho/pkg/mho"
+	e2client "github.com/onosproject/onos-ric-sdk-go/pkg/e2/v1beta1"
+	"github.com/onosproject/rimedo-ts/pkg/mho"
+)
+
+// ControlBackendMho is the control backend of the E2SM-MHO handover control
+const ControlBackendMho = "mho"
+
+// Handover is the handover of a UE from its serving cell to the target cell, both controlled by the same E2 node.
+type Handover struct {
+	UeIdentity *e2sm_v2_ies.Ueid
+	ServingCGI *e2sm_v2_ies.Cgi
+	TargetCGI  *e2sm_v2_ies.Cgi
+}
+
+// ControlBackend builds the handover control messages of a service model and sends them to the E2 nodes.
+type ControlBackend interface {
+	Name() string
+	// CreateHandoverControl builds the control message handing the UE over
+	CreateHandoverControl(handover Handover) (*e2api.ControlMessage, error)
+	// Node returns the client sending the control messages of the service model to the E2 node
+	Node(e2NodeID topoapi.ID) e2client.Node
+}
+
+// selectControlBackend chooses the backend of the node from its service models, nil if it has none the xApp
+// can control the handovers through.
+func (m *Manager) selectControlBackend(capabilities Capabilities) ControlBackend {
+	// a node whose capabilities couldn't be read is assumed to support E2SM-MHO, as it is subscribed to it
+	if capabilities.Mho || capabilities.Error != "" {
+		return &mhoControlBackend{client: m.e2client}
+	}
+	return nil
+}
+
+type mhoControlBackend struct {
+	client e2client.Client
+}
+
+func (b *mhoControlBackend) Name() string {
+	return ControlBackendMho
+}
+
+func (b *mhoControlBackend) Node(e2NodeID topoapi.ID) e2client.Node {
+	return b.client.Node(e2client.NodeID(e2NodeID))
+}
+
+func (b *mhoControlBackend) CreateHandoverControl(handover Handover) (*e2api.ControlMessage, error) {
+	controlHandler := &control.E2SmMhoControlHandler{
+		ControlAckRequest: e2tapi.ControlAckRequest_ACK,
+	}
+	servingPlmnIDBytes := mho.GetPlmnIDBytesFromCellGlobalID(handover.ServingCGI)
+	servingCellIdentity := handover.ServingCGI.GetNRCgi().GetNRcellIdentity().GetValue()
+	if mho.IsEutraCellGlobalID(handover.ServingCGI) {
+		servingCellIdentity = handover.ServingCGI.GetEUtraCgi().GetEUtracellIdentity().GetValue()
+	}
+
+	var err error
+	if controlHandler.ControlHeader, err = controlHandler.CreateMhoControlHeader(servingCellIdentity.GetValue(), servingCellIdentity.GetLen(), 1, servingPlmnIDBytes); err != nil {
+		return nil, errors.NewInvalid("control header not created: %v", err)
+	}
+	if controlHandler.ControlMessage, err = controlHandler.CreateMhoControlMessage(handover.ServingCGI, handover.UeIdentity, handover.TargetCGI); err != nil {
+		return nil, errors.NewInvalid("control message not created: %v", err)
+	}
+	return controlHandler.CreateMhoControlRequest()
+}
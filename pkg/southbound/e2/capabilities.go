@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2019-present Open Networking Foundation <info@opennetworking.org>
+// SPDX-FileCopyrightText: 2019-present Rimedo Labs
+//
+// SPDX-License-Identifier: Apache-2.0
+// Created by RIMEDO-Labs team
+
+package e2
+
+import (
+	"context"
+	"strings"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+	e2sm_mho "github.com/onosproject/onos-e2-sm/servicemodels/e2sm_mho_go/v2/e2sm-mho-go"
+)
+
+// ReportStyle is a report style of the MHO RAN function.
+type ReportStyle struct {
+	Type int32  `json:"type"`
+	Name string `json:"name"`
+}
+
+// Capabilities are what an E2 node advertises in the topology.
+//
+// The MHO RAN function in the topology only lists the report styles of the node, the supported trigger types
+// are the ones of the types of the report styles, numbered from 1 in the order of the E2SM-MHO trigger types:
+// 1 for the periodic reports, 2 for the measurement reports and 3 for the RRC state changes. If a report style
+// has another type, or the node has no report style, all trigger types are assumed to be supported.
+type Capabilities struct {
+	// ServiceModels are the names of the service models of the node by their OIDs
+	ServiceModels map[string]string `json:"serviceModels"`
+	// Mho tells whether the node has the MHO RAN function
+	Mho          bool          `json:"mho"`
+	ReportStyles []ReportStyle `json:"reportStyles,omitempty"`
+	// TriggerTypes are the trigger types of the report styles
+	TriggerTypes []string `json:"triggerTypes,omitempty"`
+	// ControlBackend is the service model the handovers of the node are controlled through, empty if none
+	ControlBackend string `json:"controlBackend"`
+	// Error is why the capabilities couldn't be read, then all trigger types are subscribed
+	Error string `json:"error,omitempty"`
+}
+
+// The E2SM-MHO report style types, numbered from 1 in the order of the MhoTriggerType values.
+const (
+	reportStyleTypePeriodic            int32 = 1
+	reportStyleTypeUponRcvMeasReport   int32 = 2
+	reportStyleTypeUponChangeRrcStatus int32 = 3
+)
+
+// reportStyleTriggerTypes are the trigger types of the E2SM-MHO report style types
+var reportStyleTriggerTypes = map[int32]e2sm_mho.MhoTriggerType{
+	reportStyleTypePeriodic:            e2sm_mho.MhoTriggerType_MHO_TRIGGER_TYPE_PERIODIC,
+	reportStyleTypeUponRcvMeasReport:   e2sm_mho.MhoTriggerType_MHO_TRIGGER_TYPE_UPON_RCV_MEAS_REPORT,
+	reportStyleTypeUponChangeRrcStatus: e2sm_mho.MhoTriggerType_MHO_TRIGGER_TYPE_UPON_CHANGE_RRC_STATUS,
+}
+
+// getCapabilities reads the service models and the MHO RAN function of the E2 node from the topology
+func (m *Manager) getCapabilities(ctx context.Context, e2NodeID topoapi.ID) Capabilities {
+	capabilities := Capabilities{
+		ServiceModels: make(map[string]string),
+	}
+	aspects, err := m.rnibClient.GetE2NodeAspects(ctx, e2NodeID)
+	if err != nil {
+		capabilities.Error = err.Error()
+		return capabilities
+	}
+	for smOID, sm := range aspects.ServiceModels {
+		capabilities.ServiceModels[smOID] = sm.Name
+		if strings.ToLower(sm.Name) == string(m.smModelName) && sm.OID == oid {
+			capabilities.Mho = true
+		}
+	}
+	if !capabilities.Mho {
+		return capabilities
+	}
+
+	ranFunction, err := m.getRanFunction(aspects.ServiceModels)
+	if err != nil {
+		capabilities.Error = err.Error()
+		return capabilities
+	}
+	for _, reportStyle := range ranFunction.ReportStyles {
+		capabilities.ReportStyles = append(capabilities.ReportStyles, ReportStyle{
+			Type: reportStyle.Type,
+			Name: reportStyle.Name,
+		})
+	}
+	supported := make(map[e2sm_mho.MhoTriggerType]bool)
+	for _, reportStyle := range capabilities.ReportStyles {
+		triggerType, ok := reportStyleTriggerTypes[reportStyle.Type]
+		if !ok {
+			log.Warnf("E2 node %v has the unknown MHO report style %v (%v), assuming all trigger types are supported",
+				e2NodeID, reportStyle.Type, reportStyle.Name)
+			return capabilities
+		}
+		supported[triggerType] = true
+	}
+	for _, triggerType := range []e2sm_mho.MhoTriggerType{
+		e2sm_mho.MhoTriggerType_MHO_TRIGGER_TYPE_PERIODIC,
+		e2sm_mho.MhoTriggerType_MHO_TRIGGER_TYPE_UPON_RCV_MEAS_REPORT,
+		e2sm_mho.MhoTriggerType_MHO_TRIGGER_TYPE_UPON_CHANGE_RRC_STATUS,
+	} {
+		if supported[triggerType] {
+			capabilities.TriggerTypes = append(capabilities.TriggerTypes, triggerType.String())
+		}
+	}
+	return capabilities
+}
+
+// supports tells whether the trigger type can be subscribed on the node; unknown capabilities support everything
+func (c Capabilities) supports(triggerType e2sm_mho.MhoTriggerType) bool {
+	if c.Error != "" {
+		return true
+	}
+	if !c.Mho {
+		return false
+	}
+	if len(c.TriggerTypes) == 0 {
+		return true
+	}
+	for _, supported := range c.TriggerTypes {
+		if supported == triggerType.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// GetCapabilities returns the capabilities of the connected E2 nodes.
+func (m *Manager) GetCapabilities() map[string]Capabilities {
+	nodes := m.registry.list()
+	output := make(map[string]Capabilities, len(nodes))
+	for e2NodeID, node := range nodes {
+		capabilities := node.capabilities
+		if backend := m.selectControlBackend(capabilities); backend != nil {
+			capabilities.ControlBackend = backend.Name()
+		}
+		output[string(e2NodeID)] = capabilities
+	}
+	return output
+}
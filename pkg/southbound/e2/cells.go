@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2019-present Open Networking Foundation <info@opennetworking.org>
+// SPDX-FileCopyrightText: 2019-present Rimedo Labs
+//
+// SPDX-License-Identifier: Apache-2.0
+// Created by RIMEDO-Labs team
+
+package e2
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/rimedo-ts/pkg/rnib"
+)
+
+func newCellState() *cellState {
+	return &cellState{
+		cells: make(map[string]rnib.Cell),
+	}
+}
+
+// cellState caches the cell types of the E2 cells of the topology, kept up to date by watchCells
+type cellState struct {
+	cells map[string]rnib.Cell
+	mu    sync.RWMutex
+}
+
+// watchCells keeps the cache of the cell types up to date until the context is done. A failed watch is created
+// again after a backoff, like the subscriptions.
+func (m *Manager) watchCells(ctx context.Context) {
+	backoff := SubscriptionInitialBackoff
+	for {
+		ch := make(chan topoapi.Event)
+		if err := m.rnibClient.WatchE2Cells(ctx, ch); err != nil {
+			log.Warnf("Couldn't watch the E2 cells, retrying in %v: %v", backoff, err)
+		} else {
+			backoff = SubscriptionInitialBackoff
+			for topoEvent := range ch {
+				m.updateCell(topoEvent)
+			}
+			log.Warnf("Watch of the E2 cells closed, retrying in %v", backoff)
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff *= 2
+		if backoff > SubscriptionMaxBackoff {
+			backoff = SubscriptionMaxBackoff
+		}
+	}
+}
+
+func (m *Manager) updateCell(topoEvent topoapi.Event) {
+	id := string(topoEvent.Object.ID)
+	m.cells.mu.Lock()
+	defer m.cells.mu.Unlock()
+	if topoEvent.Type == topoapi.EventType_REMOVED {
+		delete(m.cells.cells, id)
+		return
+	}
+	cell, err := rnib.NewCell(&topoEvent.Object)
+	if err != nil {
+		log.Warnf("Invalid E2 cell %v: %v", id, err)
+		return
+	}
+	m.cells.cells[id] = cell
+}
+
+// GetCellTypes returns the CGI and the cell type of the E2 cells of the topology, keyed by the cell ID. They are
+// read from the cache kept by the watch of the cells, so they don't cost a request to the topology.
+func (m *Manager) GetCellTypes(ctx context.Context) map[string]rnib.Cell {
+	m.cells.mu.RLock()
+	defer m.cells.mu.RUnlock()
+	cells := make(map[string]rnib.Cell, len(m.cells.cells))
+	for id, cell := range m.cells.cells {
+		cells[id] = cell
+	}
+	return cells
+}
@@ -0,0 +1,260 @@
+// SPDX-FileCopyrightText: 2019-present Open Networking Foundation <info@opennetworking.org>
+// SPDX-FileCopyrightText: 2019-present Rimedo Labs
+//
+// SPDX-License-Identifier: Apache-2.0
+// Created by RIMEDO-Labs team
+
+package e2
+
+import (
+	"context"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/onos-ric-sdk-go/pkg/e2/v1beta1/e2errors"
+)
+
+// ControlRequest is a handover to be requested from an E2 node; the control message is built by the control
+// backend of the node.
+type ControlRequest struct {
+	UeID     string
+	Handover Handover
+	// Done, if set, is called once with the outcome of the request, after the last retry
+	Done func(result ControlResult)
+}
+
+// ControlResult is the outcome of a control request. The E2T returns the control acknowledgement with its
+// outcome, or the control failure with its cause, as the result of the control call.
+type ControlResult struct {
+	NodeID   string
+	UeID     string
+	Success  bool
+	Cause    string
+	Attempts int
+	// Outcome is the hex encoded control outcome of the service model, if the E2 node sent one
+	Outcome string
+}
+
+// ControlStats counts the control requests sent to the E2 nodes by their outcome.
+type ControlStats struct {
+	Sent         uint64            `json:"sent"`
+	Acknowledged uint64            `json:"acknowledged"`
+	Failed       uint64            `json:"failed"`
+	Retried      uint64            `json:"retried"`
+	Causes       map[string]uint64 `json:"causes"`
+}
+
+// ControlRetry is how transient control failures are retried; the backoff doubles after every attempt.
+type ControlRetry struct {
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+func newControlState() *controlState {
+	return &controlState{
+		retry: ControlRetry{
+			MaxRetries:     3,
+			InitialBackoff: 100 * time.Millisecond,
+			MaxBackoff:     2 * time.Second,
+		},
+		stats: ControlStats{
+			Causes: make(map[string]uint64),
+		},
+	}
+}
+
+type controlState struct {
+	retry ControlRetry
+	stats ControlStats
+	mu    sync.RWMutex
+}
+
+func (m *Manager) SetControlRetry(retry ControlRetry) {
+	m.control.mu.Lock()
+	defer m.control.mu.Unlock()
+	m.control.retry = retry
+}
+
+func (m *Manager) GetControlStats() ControlStats {
+	m.control.mu.RLock()
+	defer m.control.mu.RUnlock()
+	stats := m.control.stats
+	stats.Causes = make(map[string]uint64, len(m.control.stats.Causes))
+	for cause, count := range m.control.stats.Causes {
+		stats.Causes[cause] = count
+	}
+	return stats
+}
+
+func (m *Manager) sendControl(ctx context.Context, e2nodeID topoapi.ID, backend ControlBackend, request *ControlRequest) {
+	m.control.mu.RLock()
+	retry := m.control.retry
+	m.control.mu.RUnlock()
+
+	result := ControlResult{
+		NodeID: string(e2nodeID),
+		UeID:   request.UeID,
+	}
+	message, cause, err := createHandoverControl(backend, request.Handover)
+	if err != nil {
+		result.Cause = cause
+		m.countControl(func(stats *ControlStats) {
+			stats.Failed++
+			stats.Causes[cause]++
+		})
+		log.Warnf("Control request for UE [ID:%v] not sent to E2 node %v - %v: %v", request.UeID, e2nodeID, cause, err)
+		if request.Done != nil {
+			request.Done(result)
+		}
+		return
+	}
+
+	node := backend.Node(e2nodeID)
+	backoff := retry.InitialBackoff
+	for {
+		result.Attempts++
+		m.countControl(func(stats *ControlStats) {
+			stats.Sent++
+		})
+		outcome, err := node.Control(ctx, message)
+		if err == nil {
+			result.Success = true
+			if outcome != nil {
+				result.Outcome = hex.EncodeToString(outcome.Payload)
+			}
+			m.countControl(func(stats *ControlStats) {
+				stats.Acknowledged++
+			})
+			log.Debugf("Control request for UE [ID:%v] acknowledged by E2 node %v after %v attempt(s)", request.UeID, e2nodeID, result.Attempts)
+			break
+		}
+
+		cause, transient := getControlFailureCause(err)
+		result.Cause = cause
+		if !transient || result.Attempts > retry.MaxRetries || ctx.Err() != nil {
+			m.countControl(func(stats *ControlStats) {
+				stats.Failed++
+				stats.Causes[cause]++
+			})
+			log.Warnf("Control request for UE [ID:%v] failed on E2 node %v after %v attempt(s) - %v: %v", request.UeID, e2nodeID, result.Attempts, cause, err)
+			break
+		}
+
+		m.countControl(func(stats *ControlStats) {
+			stats.Retried++
+		})
+		log.Infof("Control request for UE [ID:%v] failed on E2 node %v - %v, retrying in %v", request.UeID, e2nodeID, cause, backoff)
+		if cause, err := m.waitControlRetry(ctx, e2nodeID, backoff); err != nil {
+			result.Cause = cause
+			m.countControl(func(stats *ControlStats) {
+				stats.Failed++
+				stats.Causes[cause]++
+			})
+			log.Warnf("Control request for UE [ID:%v] not retried on E2 node %v after %v attempt(s) - %v: %v", request.UeID, e2nodeID, result.Attempts, cause, err)
+			break
+		}
+		backoff *= 2
+		if backoff > retry.MaxBackoff {
+			backoff = retry.MaxBackoff
+		}
+	}
+
+	if request.Done != nil {
+		request.Done(result)
+	}
+}
+
+// waitControlRetry waits for the backoff before a control request is retried. It returns the failure cause if the
+// request can't be retried because the E2 node disconnected meanwhile, which also cancels the context of the node.
+func (m *Manager) waitControlRetry(ctx context.Context, e2nodeID topoapi.ID, backoff time.Duration) (string, error) {
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return "e2-node-disconnected", ctx.Err()
+	}
+	if !m.registry.Contains(string(e2nodeID)) {
+		return "e2-node-disconnected", errors.NewUnavailable("E2 node %v disconnected", e2nodeID)
+	}
+	return "", nil
+}
+
+// createHandoverControl builds the control message with the backend of the node, the cause names why it couldn't
+func createHandoverControl(backend ControlBackend, handover Handover) (*e2api.ControlMessage, string, error) {
+	if backend == nil {
+		return nil, "no-control-backend", errors.NewNotSupported("the E2 node supports no handover control")
+	}
+	message, err := backend.CreateHandoverControl(handover)
+	if err != nil {
+		return nil, backend.Name() + "-encoding-failed", err
+	}
+	return message, "", nil
+}
+
+func (m *Manager) countControl(update func(stats *ControlStats)) {
+	m.control.mu.Lock()
+	defer m.control.mu.Unlock()
+	update(&m.control.stats)
+}
+
+var controlFailureCauses = map[e2errors.E2APType]string{
+	e2errors.RICUnspecified:                                       "ric-unspecified",
+	e2errors.RICRANFunctionIDInvalid:                              "ric-ran-function-id-invalid",
+	e2errors.RICActionNotSupported:                                "ric-action-not-supported",
+	e2errors.RICExcessiveActions:                                  "ric-excessive-actions",
+	e2errors.RICDuplicateAction:                                   "ric-duplicate-action",
+	e2errors.RICDuplicateEvent:                                    "ric-duplicate-event",
+	e2errors.RICFunctionResourceLimit:                             "ric-function-resource-limit",
+	e2errors.RICRequestIDUnknown:                                  "ric-request-id-unknown",
+	e2errors.RICInconsistentActionSubsequentActionSequence:        "ric-inconsistent-action-subsequent-action-sequence",
+	e2errors.RICControlMessageInvalid:                             "ric-control-message-invalid",
+	e2errors.RICCallProcessIDInvalid:                              "ric-call-process-id-invalid",
+	e2errors.RICServiceUnspecified:                                "ric-service-unspecified",
+	e2errors.RICServiceFunctionNotRequired:                        "ric-service-function-not-required",
+	e2errors.RICServiceExcessiveFunctions:                         "ric-service-excessive-functions",
+	e2errors.RICServiceRICResourceLimit:                           "ric-service-ric-resource-limit",
+	e2errors.ProtocolUnspecified:                                  "protocol-unspecified",
+	e2errors.ProtocolTransferSyntaxError:                          "protocol-transfer-syntax-error",
+	e2errors.ProtocolAbstractSyntaxErrorReject:                    "protocol-abstract-syntax-error-reject",
+	e2errors.ProtocolAbstractSyntaxErrorIgnoreAndNotify:           "protocol-abstract-syntax-error-ignore-and-notify",
+	e2errors.ProtocolMessageNotCompatibleWithReceiverState:        "protocol-message-not-compatible-with-receiver-state",
+	e2errors.ProtocolSemanticError:                                "protocol-semantic-error",
+	e2errors.ProtocolAbstractSyntaxErrorFalselyConstructedMessage: "protocol-abstract-syntax-error-falsely-constructed-message",
+	e2errors.MiscUnspecified:                                      "misc-unspecified",
+	e2errors.MiscControlProcessingOverload:                        "misc-control-processing-overload",
+	e2errors.MiscHardwareFailure:                                  "misc-hardware-failure",
+	e2errors.MiscOMIntervention:                                   "misc-om-intervention",
+}
+
+// getControlFailureCause names the cause of the control failure and tells whether it is worth retrying:
+// overload and resource limits of the E2 node and unavailable or timed out E2T connections are transient.
+func getControlFailureCause(err error) (string, bool) {
+	if typedErr, ok := err.(*e2errors.TypedError); ok {
+		cause, ok := controlFailureCauses[typedErr.E2APType]
+		if !ok {
+			cause = "unknown"
+		}
+		switch typedErr.E2APType {
+		case e2errors.MiscControlProcessingOverload, e2errors.RICFunctionResourceLimit, e2errors.RICServiceRICResourceLimit:
+			return cause, true
+		default:
+			return cause, false
+		}
+	}
+	switch {
+	case errors.IsUnavailable(err):
+		return "unavailable", true
+	case errors.IsTimeout(err):
+		return "timeout", true
+	case errors.IsCanceled(err):
+		return "canceled", false
+	default:
+		return "unknown", false
+	}
+}
@@ -10,7 +10,9 @@ package e2
 import (
 	"context"
 	"fmt"
+	"math"
 	"strings"
+	"sync"
 
 	prototypes "github.com/gogo/protobuf/types"
 	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
@@ -21,6 +23,7 @@ import (
 	"github.com/onosproject/onos-lib-go/pkg/logging"
 	"github.com/onosproject/onos-mho/pkg/broker"
 	e2client "github.com/onosproject/onos-ric-sdk-go/pkg/e2/v1beta1"
+	"github.com/onosproject/rimedo-ts/pkg/config"
 	"github.com/onosproject/rimedo-ts/pkg/mho"
 	"github.com/onosproject/rimedo-ts/pkg/monitoring"
 	"github.com/onosproject/rimedo-ts/pkg/rnib"
@@ -43,7 +46,7 @@ type Options struct {
 	SMVersion   string
 }
 
-func NewManager(options Options, indCh chan *mho.E2NodeIndication, ctrlReqChs map[string]chan *e2api.ControlMessage) (Manager, error) {
+func NewManager(options Options, indCh chan *mho.E2NodeIndication) (Manager, error) {
 
 	smName := e2client.ServiceModelName(options.SMName)
 	smVer := e2client.ServiceModelVersion(options.SMVersion)
@@ -65,25 +68,56 @@ func NewManager(options Options, indCh chan *mho.E2NodeIndication, ctrlReqChs ma
 	}
 
 	return Manager{
-		e2client:    e2Client,
-		rnibClient:  rnibClient,
-		streams:     broker.NewBroker(),
-		indCh:       indCh,
-		ctrlReqChs:  ctrlReqChs,
-		smModelName: smName,
+		e2client:      e2Client,
+		rnibClient:    rnibClient,
+		streams:       broker.NewBroker(),
+		indCh:         indCh,
+		registry:      NewNodeRegistry(),
+		smModelName:   smName,
+		control:       newControlState(),
+		nodes:         newNodeState(),
+		subscriptions: newSubscriptionState(),
+		cells:         newCellState(),
 	}, nil
 }
 
 type Manager struct {
-	e2client    e2client.Client
-	rnibClient  rnib.Client
-	streams     broker.Broker
-	indCh       chan *mho.E2NodeIndication
-	ctrlReqChs  map[string]chan *e2api.ControlMessage
-	smModelName e2client.ServiceModelName
+	e2client      e2client.Client
+	rnibClient    rnib.Client
+	streams       broker.Broker
+	indCh         chan *mho.E2NodeIndication
+	registry      *NodeRegistry
+	smModelName   e2client.ServiceModelName
+	control       *controlState
+	nodes         *nodeState
+	subscriptions *subscriptionState
+	cells         *cellState
+}
+
+func newNodeState() *nodeState {
+	return &nodeState{
+		triggers: func(nodeID string, labels map[string]string) config.Triggers {
+			return config.DefaultTriggers()
+		},
+	}
+}
+
+// nodeState holds the handlers applied to the nodes of the registry
+type nodeState struct {
+	removed  func(nodeID string)
+	triggers func(nodeID string, labels map[string]string) config.Triggers
+	mu       sync.Mutex
+}
+
+// SetE2NodeRemovedHandler sets the function called after an E2 node was disconnected and its subscriptions removed.
+func (m *Manager) SetE2NodeRemovedHandler(handler func(nodeID string)) {
+	m.nodes.mu.Lock()
+	defer m.nodes.mu.Unlock()
+	m.nodes.removed = handler
 }
 
 func (m *Manager) Start() error {
+	go m.watchCells(context.Background())
 	go func() {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
@@ -105,59 +139,133 @@ func (m *Manager) watchE2Connections(ctx context.Context) error {
 	}
 
 	for topoEvent := range ch {
-		if topoEvent.Type == topoapi.EventType_ADDED || topoEvent.Type == topoapi.EventType_NONE {
-			relation := topoEvent.Object.Obj.(*topoapi.Object_Relation)
-			e2NodeID := relation.Relation.TgtEntityID
-			m.ctrlReqChs[string(e2NodeID)] = make(chan *e2api.ControlMessage)
-
-			triggers := make(map[e2sm_mho.MhoTriggerType]bool)
-			triggers[e2sm_mho.MhoTriggerType_MHO_TRIGGER_TYPE_PERIODIC] = true
-			triggers[e2sm_mho.MhoTriggerType_MHO_TRIGGER_TYPE_UPON_RCV_MEAS_REPORT] = true
-			triggers[e2sm_mho.MhoTriggerType_MHO_TRIGGER_TYPE_UPON_CHANGE_RRC_STATUS] = true
-
-			for triggerType, enabled := range triggers {
-				if enabled {
-					go func(triggerType e2sm_mho.MhoTriggerType) {
-						_ = m.createSubscription(ctx, e2NodeID, triggerType)
-					}(triggerType)
+		relation, ok := topoEvent.Object.Obj.(*topoapi.Object_Relation)
+		if !ok {
+			continue
+		}
+		e2NodeID := relation.Relation.TgtEntityID
+		switch topoEvent.Type {
+		case topoapi.EventType_ADDED, topoapi.EventType_NONE:
+			// a node connected again before its removal was seen is subscribed from scratch
+			if m.stopE2Node(ctx, e2NodeID) {
+				log.Infof("E2 node %v reconnected, resubscribing", e2NodeID)
+			}
+			m.startE2Node(ctx, e2NodeID)
+		case topoapi.EventType_REMOVED:
+			if m.stopE2Node(ctx, e2NodeID) {
+				log.Infof("E2 node %v disconnected", e2NodeID)
+				m.nodes.mu.Lock()
+				removed := m.nodes.removed
+				m.nodes.mu.Unlock()
+				if removed != nil {
+					removed(string(e2NodeID))
 				}
 			}
-			go m.watchMHOChanges(ctx, e2NodeID)
 		}
 	}
 
 	return nil
 }
 
-func (m *Manager) watchMHOChanges(ctx context.Context, e2nodeID topoapi.ID) {
-
-	for ctrlReqMsg := range m.ctrlReqChs[string(e2nodeID)] {
-		go func(ctrlReqMsg *e2api.ControlMessage) {
-			node := m.e2client.Node(e2client.NodeID(e2nodeID))
-			_, _ = node.Control(ctx, ctrlReqMsg)
-		}(ctrlReqMsg)
+func (m *Manager) startE2Node(ctx context.Context, e2NodeID topoapi.ID) {
+	labels, err := m.rnibClient.GetE2NodeLabels(ctx, e2NodeID)
+	if err != nil {
+		log.Warnf("Cannot get the labels of E2 node %v, its triggers are chosen by its ID only: %v", e2NodeID, err)
+	}
+	capabilities := m.getCapabilities(ctx, e2NodeID)
+	if capabilities.Error != "" {
+		log.Warnf("Cannot get the capabilities of E2 node %v, subscribing all its triggers: %v", e2NodeID, capabilities.Error)
+	}
+	if capabilities.Error == "" && m.selectControlBackend(capabilities) == nil {
+		log.Warnf("E2 node %v doesn't advertise E2SM-MHO handover control, its UEs can't be handed over", e2NodeID)
 	}
+	nodeCtx, cancel := context.WithCancel(ctx)
+	node := &connectedNode{
+		ctx:          nodeCtx,
+		cancel:       cancel,
+		labels:       labels,
+		capabilities: capabilities,
+		controlQueue: make(chan *ControlRequest, ControlQueueSize),
+	}
+	m.registry.add(e2NodeID, node)
+
+	m.subscribeE2Node(e2NodeID, node)
+	go m.watchMHOChanges(e2NodeID, node)
 }
 
-func (m *Manager) createSubscription(ctx context.Context, e2nodeID topoapi.ID, triggerType e2sm_mho.MhoTriggerType) error {
-	eventTriggerData, err := m.createEventTrigger(triggerType)
-	if err != nil {
-		return err
+// stopE2Node removes the subscriptions of the E2 node and stops its monitors and control requests.
+// It returns false if the node wasn't started.
+func (m *Manager) stopE2Node(ctx context.Context, e2NodeID topoapi.ID) bool {
+	node, ok := m.registry.remove(e2NodeID)
+	if !ok {
+		return false
 	}
 
-	actions := m.createSubscriptionActions()
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	if node.subscriptions != nil {
+		m.closeSubscriptions(ctx, e2NodeID, node.subscriptions)
+	}
+	node.cancel()
+	return true
+}
 
-	aspects, err := m.rnibClient.GetE2NodeAspects(ctx, e2nodeID)
-	if err != nil {
-		return err
+// closeSubscriptions removes the subscriptions of the group and stops their supervisors.
+func (m *Manager) closeSubscriptions(ctx context.Context, e2NodeID topoapi.ID, group *subscriptionGroup) {
+	group.mu.Lock()
+	defer group.mu.Unlock()
+	for subName, channelID := range group.channels {
+		if _, err := m.streams.CloseStream(ctx, channelID); err != nil {
+			log.Warnf("Cannot remove subscription %v of E2 node %v: %v", subName, e2NodeID, err)
+		}
+	}
+	group.channels = make(map[string]e2api.ChannelID)
+	group.cancel()
+}
+
+// watchMHOChanges sends the control requests queued for the node; the ones still queued when the node is stopped fail.
+func (m *Manager) watchMHOChanges(e2nodeID topoapi.ID, node *connectedNode) {
+
+	for {
+		select {
+		case ctrlReq := <-node.controlQueue:
+			go m.sendControl(node.ctx, e2nodeID, m.selectControlBackend(node.capabilities), ctrlReq)
+		case <-node.ctx.Done():
+			for {
+				select {
+				case ctrlReq := <-node.controlQueue:
+					log.Warnf("Control request for UE [ID:%v] not sent - E2 node %v disconnected", ctrlReq.UeID, e2nodeID)
+					if ctrlReq.Done != nil {
+						ctrlReq.Done(ControlResult{NodeID: string(e2nodeID), UeID: ctrlReq.UeID, Cause: "e2-node-disconnected"})
+					}
+				default:
+					return
+				}
+			}
+		}
 	}
+}
+
+// GetNodeRegistry returns the registry of the connected E2 nodes.
+func (m *Manager) GetNodeRegistry() *NodeRegistry {
+	return m.registry
+}
+
+// SendControl queues the control request of the E2 node, see NodeRegistry.SendControl.
+func (m *Manager) SendControl(nodeID string, request *ControlRequest) error {
+	return m.registry.SendControl(nodeID, request)
+}
 
-	_, err = m.getRanFunction(aspects.ServiceModels)
+// createSubscription subscribes the trigger type on the E2 node and forwards its indications until the stream fails
+// or the context is done. onActive is called once the subscription is established.
+func (m *Manager) createSubscription(ctx context.Context, group *subscriptionGroup, e2nodeID topoapi.ID, triggerType e2sm_mho.MhoTriggerType, onActive func()) error {
+	eventTriggerData, err := m.createEventTrigger(triggerType, group.triggers.ReportingPeriod)
 	if err != nil {
 		return err
 	}
 
-	ch := make(chan e2api.Indication)
+	actions := m.createSubscriptionActions()
+
 	node := m.e2client.Node(e2client.NodeID(e2nodeID))
 	subName := fmt.Sprintf("rimedo-ts-subscription-%s", triggerType)
 	subSpec := e2api.SubscriptionSpec{
@@ -167,6 +275,17 @@ func (m *Manager) createSubscription(ctx context.Context, e2nodeID topoapi.ID, t
 		},
 	}
 
+	return m.subscribe(ctx, group, node, e2nodeID, subName, subSpec, onActive, func(streamReader broker.StreamReader) error {
+		monitor := monitoring.NewMonitor(streamReader, e2nodeID, m.indCh, triggerType)
+		return monitor.Start(ctx)
+	})
+}
+
+// subscribe creates the subscription on the E2 node and reads its indications with read until the stream fails
+// or the context is done. onActive is called once the subscription is established.
+func (m *Manager) subscribe(ctx context.Context, group *subscriptionGroup, node e2client.Node, e2nodeID topoapi.ID, subName string,
+	subSpec e2api.SubscriptionSpec, onActive func(), read func(streamReader broker.StreamReader) error) error {
+	ch := make(chan e2api.Indication)
 	channelID, err := node.Subscribe(ctx, subName, subSpec, ch)
 	if err != nil {
 		log.Warn(err)
@@ -177,16 +296,31 @@ func (m *Manager) createSubscription(ctx context.Context, e2nodeID topoapi.ID, t
 	if err != nil {
 		return err
 	}
+	group.mu.Lock()
+	if ctx.Err() != nil {
+		// the node was stopped or subscribed again while subscribing
+		group.mu.Unlock()
+		_, _ = m.streams.CloseStream(context.Background(), channelID)
+		return ctx.Err()
+	}
+	group.channels[subName] = channelID
+	group.mu.Unlock()
 	go m.sendIndicationOnStream(streamReader.StreamID(), ch)
 
-	monitor := monitoring.NewMonitor(streamReader, e2nodeID, m.indCh, triggerType)
-
-	err = monitor.Start(ctx)
-	if err != nil {
-		log.Warn(err)
+	onActive()
+	err = read(streamReader)
+	if ctx.Err() != nil {
+		return ctx.Err()
 	}
 
-	return nil
+	// the stream failed, the subscription is removed before it is created again
+	group.mu.Lock()
+	delete(group.channels, subName)
+	group.mu.Unlock()
+	if _, closeErr := m.streams.CloseStream(ctx, channelID); closeErr != nil {
+		log.Warnf("Cannot remove subscription %v of E2 node %v: %v", subName, e2nodeID, closeErr)
+	}
+	return err
 }
 
 func (m *Manager) getRanFunction(serviceModelsInfo map[string]*topoapi.ServiceModelInfo) (*topoapi.MHORanFunction, error) {
@@ -209,10 +343,12 @@ func (m *Manager) getRanFunction(serviceModelsInfo map[string]*topoapi.ServiceMo
 
 }
 
-func (m *Manager) createEventTrigger(triggerType e2sm_mho.MhoTriggerType) ([]byte, error) {
+func (m *Manager) createEventTrigger(triggerType e2sm_mho.MhoTriggerType, reportingPeriod uint64) ([]byte, error) {
 	var reportPeriodMs int32
-	reportingPeriod := 1000
 	if triggerType == e2sm_mho.MhoTriggerType_MHO_TRIGGER_TYPE_PERIODIC {
+		if reportingPeriod == 0 || reportingPeriod > math.MaxInt32 {
+			return []byte{}, errors.New(errors.Invalid, "invalid reporting period %v ms", reportingPeriod)
+		}
 		reportPeriodMs = int32(reportingPeriod)
 	} else {
 		reportPeriodMs = 0
@@ -257,6 +393,11 @@ func (m *Manager) sendIndicationOnStream(streamID broker.StreamID, ch chan e2api
 		return
 	}
 
+	// closing the stream ends the monitor reading it, so that a failed subscription is noticed
+	defer func() {
+		_ = streamWriter.Close()
+	}()
+
 	for msg := range ch {
 		err := streamWriter.Send(msg)
 		if err != nil {
@@ -266,14 +407,6 @@ func (m *Manager) sendIndicationOnStream(streamID broker.StreamID, ch chan e2api
 	}
 }
 
-func (m *Manager) GetCellTypes(ctx context.Context) map[string]rnib.Cell {
-	cellTypes, err := m.rnibClient.GetCellTypes(ctx)
-	if err != nil {
-		log.Warn(err)
-	}
-	return cellTypes
-}
-
 func (m *Manager) SetCellType(ctx context.Context, cellID string, cellType string) error {
 	err := m.rnibClient.SetCellType(ctx, cellID, cellType)
 	if err != nil {
@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2019-present Open Networking Foundation <info@opennetworking.org>
+// SPDX-FileCopyrightText: 2019-present Rimedo Labs
+//
+// SPDX-License-Identifier: Apache-2.0
+// Created by RIMEDO-Labs team
+
+package e2
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+	"github.com/onosproject/onos-lib-go/pkg/errors"
+	"github.com/onosproject/rimedo-ts/pkg/config"
+)
+
+// ControlQueueSize is how many control requests can wait to be sent to an E2 node
+const ControlQueueSize = 64
+
+// NodeRegistry holds the E2 nodes connected to the E2T with their control queues, subscriptions and capabilities.
+// It is safe for concurrent use.
+type NodeRegistry struct {
+	nodes map[topoapi.ID]*connectedNode
+	mu    sync.RWMutex
+}
+
+// NewNodeRegistry creates an empty registry.
+func NewNodeRegistry() *NodeRegistry {
+	return &NodeRegistry{
+		nodes: make(map[topoapi.ID]*connectedNode),
+	}
+}
+
+// connectedNode is an E2 node connected to the E2T; cancel stops its subscriptions, monitors and control requests.
+type connectedNode struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	labels map[string]string
+	// capabilities are read when the node connects
+	capabilities Capabilities
+	// controlQueue holds the control requests waiting to be sent to the node
+	controlQueue chan *ControlRequest
+	// subscriptions are replaced when the triggers of the node change
+	subscriptions *subscriptionGroup
+	mu            sync.Mutex
+}
+
+// subscriptionGroup holds the subscriptions of an E2 node made for the same triggers; cancel stops their supervisors.
+type subscriptionGroup struct {
+	triggers config.Triggers
+	cancel   context.CancelFunc
+	channels map[string]e2api.ChannelID
+	mu       sync.Mutex
+}
+
+func (r *NodeRegistry) add(e2NodeID topoapi.ID, node *connectedNode) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nodes[e2NodeID] = node
+}
+
+func (r *NodeRegistry) remove(e2NodeID topoapi.ID) (*connectedNode, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	node, ok := r.nodes[e2NodeID]
+	if ok {
+		delete(r.nodes, e2NodeID)
+	}
+	return node, ok
+}
+
+func (r *NodeRegistry) get(e2NodeID topoapi.ID) (*connectedNode, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	node, ok := r.nodes[e2NodeID]
+	if !ok {
+		return nil, errors.NewNotFound("E2 node %v is not connected", e2NodeID)
+	}
+	return node, nil
+}
+
+// list returns a snapshot of the connected nodes
+func (r *NodeRegistry) list() map[topoapi.ID]*connectedNode {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	nodes := make(map[topoapi.ID]*connectedNode, len(r.nodes))
+	for e2NodeID, node := range r.nodes {
+		nodes[e2NodeID] = node
+	}
+	return nodes
+}
+
+// IDs returns the IDs of the connected nodes, sorted.
+func (r *NodeRegistry) IDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ids := make([]string, 0, len(r.nodes))
+	for e2NodeID := range r.nodes {
+		ids = append(ids, string(e2NodeID))
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Contains tells whether the node is connected.
+func (r *NodeRegistry) Contains(nodeID string) bool {
+	_, err := r.get(topoapi.ID(nodeID))
+	return err == nil
+}
+
+// Capabilities returns the capabilities of the node, a NotFound error if it isn't connected.
+func (r *NodeRegistry) Capabilities(nodeID string) (Capabilities, error) {
+	node, err := r.get(topoapi.ID(nodeID))
+	if err != nil {
+		return Capabilities{}, err
+	}
+	return node.capabilities, nil
+}
+
+// SendControl queues the control request of the node without blocking. It returns a NotFound error if the node
+// isn't connected and an Unavailable error if it was disconnected meanwhile or its control queue is full.
+func (r *NodeRegistry) SendControl(nodeID string, request *ControlRequest) error {
+	node, err := r.get(topoapi.ID(nodeID))
+	if err != nil {
+		return err
+	}
+	select {
+	case <-node.ctx.Done():
+		return errors.NewUnavailable("E2 node %v was disconnected", nodeID)
+	default:
+	}
+	select {
+	case node.controlQueue <- request:
+		return nil
+	default:
+		return errors.NewUnavailable("control queue of E2 node %v is full", nodeID)
+	}
+}
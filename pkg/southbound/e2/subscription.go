@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: 2019-present Open Networking Foundation <info@opennetworking.org>
+// SPDX-FileCopyrightText: 2019-present Rimedo Labs
+//
+// SPDX-License-Identifier: Apache-2.0
+// Created by RIMEDO-Labs team
+
+package e2
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+)
+
+const (
+	// SubscriptionInitialBackoff is the wait before the first retry of a failed subscription, doubled after
+	// every failed attempt up to SubscriptionMaxBackoff
+	SubscriptionInitialBackoff = 1 * time.Second
+	SubscriptionMaxBackoff     = 30 * time.Second
+)
+
+// Subscription states
+const (
+	SubscriptionSubscribing = "SUBSCRIBING"
+	SubscriptionActive      = "ACTIVE"
+	SubscriptionRetrying    = "RETRYING"
+)
+
+// SubscriptionHealth is the state of the subscription of a trigger type on an E2 node.
+type SubscriptionHealth struct {
+	NodeID  string `json:"nodeId"`
+	Trigger string `json:"trigger"`
+	State   string `json:"state"`
+	// Since is when the subscription entered its state
+	Since time.Time `json:"since"`
+	// Failures counts the failed subscriptions and the failed indication streams
+	Failures  uint64 `json:"failures"`
+	LastError string `json:"lastError,omitempty"`
+	// NextRetry is when a retrying subscription is attempted again
+	NextRetry *time.Time `json:"nextRetry,omitempty"`
+}
+
+// subscriptionKey identifies a subscription by its node and its trigger, the name of an MHO trigger type
+type subscriptionKey struct {
+	nodeID  topoapi.ID
+	trigger string
+}
+
+func newSubscriptionState() *subscriptionState {
+	return &subscriptionState{
+		health: make(map[subscriptionKey]*subscriptionHealth),
+	}
+}
+
+type subscriptionState struct {
+	health map[subscriptionKey]*subscriptionHealth
+	mu     sync.RWMutex
+}
+
+// subscriptionHealth is owned by the last subscription group of the node that updated it, so that the supervisor
+// of a previous connection of a reconnected node, or of its previous triggers, doesn't remove it.
+type subscriptionHealth struct {
+	SubscriptionHealth
+	owner *subscriptionGroup
+}
+
+// GetSubscriptionHealth returns the state of the subscriptions of the connected E2 nodes.
+func (m *Manager) GetSubscriptionHealth() []SubscriptionHealth {
+	m.subscriptions.mu.RLock()
+	defer m.subscriptions.mu.RUnlock()
+	health := make([]SubscriptionHealth, 0, len(m.subscriptions.health))
+	for _, h := range m.subscriptions.health {
+		health = append(health, h.SubscriptionHealth)
+	}
+	sort.Slice(health, func(i, j int) bool {
+		if health[i].NodeID != health[j].NodeID {
+			return health[i].NodeID < health[j].NodeID
+		}
+		return health[i].Trigger < health[j].Trigger
+	})
+	return health
+}
+
+// superviseSubscription keeps the trigger subscribed on the E2 node until the context is done: failed
+// subscriptions and indication streams are retried with an exponential backoff. subscribe returns once the
+// subscription failed or the context is done, onActive is to be called once the subscription is established.
+func (m *Manager) superviseSubscription(ctx context.Context, group *subscriptionGroup, e2nodeID topoapi.ID, trigger string,
+	subscribe func(ctx context.Context, onActive func()) error) {
+	key := subscriptionKey{nodeID: e2nodeID, trigger: trigger}
+	defer m.updateSubscriptionHealth(group, key, func(health *SubscriptionHealth) bool {
+		return false
+	})
+
+	backoff := SubscriptionInitialBackoff
+	for {
+		m.updateSubscriptionHealth(group, key, func(health *SubscriptionHealth) bool {
+			health.State = SubscriptionSubscribing
+			health.Since = time.Now()
+			health.NextRetry = nil
+			return true
+		})
+		activated := false
+		err := subscribe(ctx, func() {
+			activated = true
+			m.updateSubscriptionHealth(group, key, func(health *SubscriptionHealth) bool {
+				health.State = SubscriptionActive
+				health.Since = time.Now()
+				return true
+			})
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if activated {
+			backoff = SubscriptionInitialBackoff
+		}
+		nextRetry := time.Now().Add(backoff)
+		m.updateSubscriptionHealth(group, key, func(health *SubscriptionHealth) bool {
+			health.State = SubscriptionRetrying
+			health.Since = time.Now()
+			health.Failures++
+			if err != nil {
+				health.LastError = err.Error()
+			}
+			health.NextRetry = &nextRetry
+			return true
+		})
+		log.Warnf("Subscription %v of E2 node %v failed, retrying in %v: %v", trigger, e2nodeID, backoff, err)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff *= 2
+		if backoff > SubscriptionMaxBackoff {
+			backoff = SubscriptionMaxBackoff
+		}
+	}
+}
+
+// updateSubscriptionHealth applies the update to the health of the subscription, which is removed if the update returns false.
+func (m *Manager) updateSubscriptionHealth(group *subscriptionGroup, key subscriptionKey, update func(health *SubscriptionHealth) bool) {
+	m.subscriptions.mu.Lock()
+	defer m.subscriptions.mu.Unlock()
+	health, ok := m.subscriptions.health[key]
+	if !ok || health.owner != group {
+		health = &subscriptionHealth{
+			SubscriptionHealth: SubscriptionHealth{
+				NodeID:  string(key.nodeID),
+				Trigger: key.trigger,
+			},
+			owner: group,
+		}
+	}
+	if update(&health.SubscriptionHealth) {
+		m.subscriptions.health[key] = health
+	} else if ok && m.subscriptions.health[key].owner == group {
+		delete(m.subscriptions.health, key)
+	}
+}
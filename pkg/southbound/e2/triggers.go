@@ -0,0 +1,121 @@
+// SPDX-FileCopyrightText: 2019-present Open Networking Foundation <info@opennetworking.org>
+// SPDX-FileCopyrightText: 2019-present Rimedo Labs
+//
+// SPDX-License-Identifier: Apache-2.0
+// Created by RIMEDO-Labs team
+
+package e2
+
+import (
+	"context"
+
+	e2api "github.com/onosproject/onos-api/go/onos/e2t/e2/v1beta1"
+	topoapi "github.com/onosproject/onos-api/go/onos/topo"
+	e2sm_mho "github.com/onosproject/onos-e2-sm/servicemodels/e2sm_mho_go/v2/e2sm-mho-go"
+	"github.com/onosproject/rimedo-ts/pkg/config"
+)
+
+// getTriggerTypes returns the trigger types to subscribe
+func getTriggerTypes(t config.Triggers) []e2sm_mho.MhoTriggerType {
+	triggerTypes := make([]e2sm_mho.MhoTriggerType, 0, 3)
+	if t.Periodic {
+		triggerTypes = append(triggerTypes, e2sm_mho.MhoTriggerType_MHO_TRIGGER_TYPE_PERIODIC)
+	}
+	if t.UponRcvMeasReport {
+		triggerTypes = append(triggerTypes, e2sm_mho.MhoTriggerType_MHO_TRIGGER_TYPE_UPON_RCV_MEAS_REPORT)
+	}
+	if t.UponChangeRrcStatus {
+		triggerTypes = append(triggerTypes, e2sm_mho.MhoTriggerType_MHO_TRIGGER_TYPE_UPON_CHANGE_RRC_STATUS)
+	}
+	return triggerTypes
+}
+
+// isSameSubscription tells whether both triggers subscribe the same trigger types with the same reporting period;
+// A3OffsetRange and HysteresisRange are not part of the E2SM-MHO event trigger, a change doesn't resubscribe the node
+func isSameSubscription(t config.Triggers, other config.Triggers) bool {
+	return t.Periodic == other.Periodic && t.UponRcvMeasReport == other.UponRcvMeasReport &&
+		t.UponChangeRrcStatus == other.UponChangeRrcStatus &&
+		(!t.Periodic || t.ReportingPeriod == other.ReportingPeriod)
+}
+
+// SetTriggers sets the function choosing the triggers of an E2 node from its ID and topology labels.
+// The connected nodes whose triggers changed are subscribed again.
+func (m *Manager) SetTriggers(triggers func(nodeID string, labels map[string]string) config.Triggers) {
+	m.nodes.mu.Lock()
+	m.nodes.triggers = triggers
+	m.nodes.mu.Unlock()
+
+	// a node added meanwhile is subscribed with the new triggers when it is started
+	for e2NodeID, node := range m.registry.list() {
+		m.subscribeE2Node(e2NodeID, node)
+	}
+}
+
+// GetTriggers returns the triggers of the connected E2 nodes.
+func (m *Manager) GetTriggers() map[string]config.Triggers {
+	nodes := m.registry.list()
+	output := make(map[string]config.Triggers, len(nodes))
+	for e2NodeID, node := range nodes {
+		node.mu.Lock()
+		if node.subscriptions != nil {
+			output[string(e2NodeID)] = node.subscriptions.triggers
+		}
+		node.mu.Unlock()
+	}
+	return output
+}
+
+// subscribeE2Node subscribes the triggers of the E2 node, unless it is already subscribed to them;
+// the subscriptions for other triggers are removed first.
+func (m *Manager) subscribeE2Node(e2NodeID topoapi.ID, node *connectedNode) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	if node.ctx.Err() != nil {
+		return
+	}
+	m.nodes.mu.Lock()
+	triggers := m.nodes.triggers(string(e2NodeID), node.labels)
+	m.nodes.mu.Unlock()
+
+	if node.subscriptions != nil {
+		if isSameSubscription(node.subscriptions.triggers, triggers) {
+			node.subscriptions.triggers = triggers
+			return
+		}
+		log.Infof("Triggers of E2 node %v changed to %+v, resubscribing", e2NodeID, triggers)
+		m.closeSubscriptions(node.ctx, e2NodeID, node.subscriptions)
+	}
+
+	ctx, cancel := context.WithCancel(node.ctx)
+	group := &subscriptionGroup{
+		triggers: triggers,
+		cancel:   cancel,
+		channels: make(map[string]e2api.ChannelID),
+	}
+	node.subscriptions = group
+	m.subscribeMho(ctx, group, e2NodeID, node.capabilities)
+}
+
+// subscribeMho supervises the subscriptions of the MHO triggers of the node it supports
+func (m *Manager) subscribeMho(ctx context.Context, group *subscriptionGroup, e2NodeID topoapi.ID, capabilities Capabilities) {
+	triggerTypes := getTriggerTypes(group.triggers)
+	if len(triggerTypes) == 0 {
+		log.Warnf("No trigger enabled for E2 node %v, it isn't subscribed", e2NodeID)
+		return
+	}
+	if !capabilities.Mho && capabilities.Error == "" {
+		log.Warnf("E2 node %v doesn't advertise the MHO service model, it isn't subscribed", e2NodeID)
+		return
+	}
+	for _, triggerType := range triggerTypes {
+		if !capabilities.supports(triggerType) {
+			log.Warnf("E2 node %v doesn't advertise a report style for %v (report styles %v), the trigger isn't subscribed",
+				e2NodeID, triggerType, capabilities.ReportStyles)
+			continue
+		}
+		triggerType := triggerType
+		go m.superviseSubscription(ctx, group, e2NodeID, triggerType.String(), func(ctx context.Context, onActive func()) error {
+			return m.createSubscription(ctx, group, e2NodeID, triggerType, onActive)
+		})
+	}
+}
@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: 2019-present Open Networking Foundation <info@opennetworking.org>
+// SPDX-FileCopyrightText: 2019-present Rimedo Labs
+//
+// SPDX-License-Identifier: Apache-2.0
+// Created by RIMEDO-Labs team
+
+package steering
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// NewQueue creates the queue of the UEs whose steering decision has to be re-evaluated.
+func NewQueue() *Queue {
+	return &Queue{
+		pending:  make(map[string]bool),
+		deferred: make(map[string]time.Time),
+		signal:   make(chan struct{}, 1),
+		mu:       sync.Mutex{},
+	}
+}
+
+// Queue coalesces the events: a UE added many times before the queue is taken is evaluated once.
+// Adding never blocks, so it is safe to call from the indication handlers.
+type Queue struct {
+	pending  map[string]bool
+	deferred map[string]time.Time
+	all      bool
+	signal   chan struct{}
+	mu       sync.Mutex
+}
+
+// Add queues the UEs for evaluation.
+func (q *Queue) Add(ueIDs ...string) {
+	q.mu.Lock()
+	for _, ueID := range ueIDs {
+		q.pending[ueID] = true
+	}
+	q.mu.Unlock()
+	q.notify()
+}
+
+// AddAll queues every UE for evaluation.
+func (q *Queue) AddAll() {
+	q.mu.Lock()
+	q.all = true
+	q.mu.Unlock()
+	q.notify()
+}
+
+// AddAt queues the UE for evaluation at the given time, e.g. when its time-to-trigger expires.
+// If the UE is already deferred the earlier time is kept.
+func (q *Queue) AddAt(ueID string, at time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if current, ok := q.deferred[ueID]; !ok || at.Before(current) {
+		q.deferred[ueID] = at
+	}
+}
+
+// Signal is notified when UEs are queued.
+func (q *Queue) Signal() <-chan struct{} {
+	return q.signal
+}
+
+// NextDeadline returns the earliest time a deferred UE is due.
+func (q *Queue) NextDeadline() (time.Time, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var next time.Time
+	found := false
+	for _, at := range q.deferred {
+		if !found || at.Before(next) {
+			next = at
+			found = true
+		}
+	}
+	return next, found
+}
+
+// Take empties the queue and returns the sorted UEs due by now, or true if every UE has to be evaluated.
+func (q *Queue) Take(now time.Time) ([]string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for ueID, at := range q.deferred {
+		if !at.After(now) {
+			q.pending[ueID] = true
+			delete(q.deferred, ueID)
+		}
+	}
+	all := q.all
+	ueIDs := make([]string, 0, len(q.pending))
+	for ueID := range q.pending {
+		ueIDs = append(ueIDs, ueID)
+	}
+	sort.Strings(ueIDs)
+	q.pending = make(map[string]bool)
+	q.all = false
+	return ueIDs, all
+}
+
+func (q *Queue) notify() {
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2019-present Open Networking Foundation <info@opennetworking.org>
+// SPDX-FileCopyrightText: 2019-present Rimedo Labs
+//
+// SPDX-License-Identifier: Apache-2.0
+// Created by RIMEDO-Labs team
+
+package steering
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestQueueDedup(t *testing.T) {
+	queue := NewQueue()
+	queue.Add("ue2", "ue1")
+	queue.Add("ue1")
+	queue.Add("ue2", "ue3")
+
+	select {
+	case <-queue.Signal():
+	default:
+		t.Fatal("queue not signaled")
+	}
+	select {
+	case <-queue.Signal():
+		t.Fatal("queue signaled more than once before it was taken")
+	default:
+	}
+
+	ueIDs, all := queue.Take(time.Now())
+	if all {
+		t.Error("all UEs taken, only the added ones expected")
+	}
+	if expected := []string{"ue1", "ue2", "ue3"}; !reflect.DeepEqual(ueIDs, expected) {
+		t.Errorf("UEs %v taken, %v expected", ueIDs, expected)
+	}
+	if ueIDs, all := queue.Take(time.Now()); len(ueIDs) != 0 || all {
+		t.Errorf("UEs %v (all %v) taken again from the emptied queue", ueIDs, all)
+	}
+}
+
+func TestQueueAddAll(t *testing.T) {
+	queue := NewQueue()
+	queue.Add("ue1")
+	queue.AddAll()
+
+	if _, all := queue.Take(time.Now()); !all {
+		t.Error("all UEs not taken")
+	}
+	if _, all := queue.Take(time.Now()); all {
+		t.Error("all UEs taken again from the emptied queue")
+	}
+}
+
+func TestQueueDeferred(t *testing.T) {
+	queue := NewQueue()
+	now := time.Now()
+	queue.AddAt("ue1", now.Add(2*time.Second))
+	queue.AddAt("ue1", now.Add(time.Second))
+	queue.AddAt("ue1", now.Add(3*time.Second))
+	queue.AddAt("ue2", now.Add(5*time.Second))
+
+	if deadline, ok := queue.NextDeadline(); !ok || !deadline.Equal(now.Add(time.Second)) {
+		t.Errorf("next deadline %v, the earliest %v expected", deadline, now.Add(time.Second))
+	}
+	if ueIDs, _ := queue.Take(now); len(ueIDs) != 0 {
+		t.Errorf("UEs %v taken before they are due", ueIDs)
+	}
+
+	// a deferred UE also added meanwhile is taken once
+	queue.Add("ue1")
+	if ueIDs, _ := queue.Take(now.Add(time.Second)); !reflect.DeepEqual(ueIDs, []string{"ue1"}) {
+		t.Errorf("UEs %v taken, ue1 expected", ueIDs)
+	}
+	if deadline, ok := queue.NextDeadline(); !ok || !deadline.Equal(now.Add(5*time.Second)) {
+		t.Errorf("next deadline %v, %v of ue2 expected", deadline, now.Add(5*time.Second))
+	}
+	if ueIDs, _ := queue.Take(now.Add(5 * time.Second)); !reflect.DeepEqual(ueIDs, []string{"ue2"}) {
+		t.Errorf("UEs %v taken, ue2 expected", ueIDs)
+	}
+	if _, ok := queue.NextDeadline(); ok {
+		t.Error("deadline left in the emptied queue")
+	}
+}
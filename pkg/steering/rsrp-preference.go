@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: 2019-present Open Networking Foundation <info@opennetworking.org>
+// SPDX-FileCopyrightText: 2019-present Rimedo Labs
+//
+// SPDX-License-Identifier: Apache-2.0
+// Created by RIMEDO-Labs team
+
+package steering
+
+import (
+	"fmt"
+
+	policyAPI "github.com/onosproject/onos-a1-dm/go/policy_schemas/traffic_steering_preference/v2"
+	"github.com/onosproject/rimedo-ts/pkg/policy"
+)
+
+func init() {
+	Register(DefaultAlgorithm, func(scorer PolicyScorer, parameters map[string]interface{}) (SteeringAlgorithm, error) {
+		return &rsrpPreference{
+			scorer: scorer,
+		}, nil
+	})
+}
+
+// rsrpPreference applies SHALL and FORBID as constraints and picks the candidate with the highest score
+// combining RSRP, policy preference and load, as configured in the scoring.
+type rsrpPreference struct {
+	scorer PolicyScorer
+}
+
+func (a *rsrpPreference) Name() string {
+	return DefaultAlgorithm
+}
+
+func (a *rsrpPreference) Decide(input Input) Decision {
+	rsrps := make([]int, len(input.Candidates))
+	loads := make([]float64, len(input.Candidates))
+	cellIDs := make([]policyAPI.CellID, len(input.Candidates))
+	for i, candidate := range input.Candidates {
+		rsrps[i] = candidate.Rsrp
+		loads[i] = candidate.Load
+		cellIDs[i] = candidate.CellID
+	}
+
+	tsResult, scores, feasible := a.scorer.GetTsResultForPoliciesV2(input.Scope, input.Policies, rsrps, loads, cellIDs)
+	if !feasible {
+		return Decision{
+			Reason: "no candidate cell satisfies the SHALL/FORBID constraints",
+		}
+	}
+
+	decision := Decision{
+		Scores: make(map[string]float64, len(input.Candidates)),
+	}
+	for i := range input.Candidates {
+		decision.Scores[input.Candidates[i].CGIString] = scores[i]
+		if decision.Target == nil && policy.IsSameCellV2(input.Candidates[i].CellID, tsResult) {
+			decision.Target = &input.Candidates[i]
+			decision.Reason = fmt.Sprintf("highest score %.2f (RSRP:%v, load:%.2f, preference:%v)", scores[i], input.Candidates[i].Rsrp,
+				input.Candidates[i].Load, a.scorer.GetPreferenceFromPoliciesV2(input.Policies, input.Candidates[i].CellID))
+		}
+	}
+	return decision
+}
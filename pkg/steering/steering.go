@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: 2019-present Open Networking Foundation <info@opennetworking.org>
+// SPDX-FileCopyrightText: 2019-present Rimedo Labs
+//
+// SPDX-License-Identifier: Apache-2.0
+// Created by RIMEDO-Labs team
+
+package steering
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	policyAPI "github.com/onosproject/onos-a1-dm/go/policy_schemas/traffic_steering_preference/v2"
+	"github.com/onosproject/onos-lib-go/pkg/logging"
+	"github.com/onosproject/rimedo-ts/pkg/mho"
+	"github.com/onosproject/rimedo-ts/pkg/policy"
+)
+
+var log = logging.GetLogger("rimedo-ts", "steering")
+
+// DefaultAlgorithm is used when the configuration doesn't select any algorithm.
+const DefaultAlgorithm = "rsrp-preference"
+
+// Candidate is a cell measured by the UE.
+type Candidate struct {
+	CellID    policyAPI.CellID
+	CGIString string
+	Rsrp      int
+	// Load is the ratio of the UEs the cell would serve with the UE attached to its capacity, 0 if unknown
+	Load    float64
+	Serving bool
+}
+
+// Input is the snapshot a steering decision is made on.
+type Input struct {
+	Ue mho.UeData
+	// Scope identifies the UE, its slice and serving cell; it carries no QoS, see QosFlows
+	Scope policyAPI.Scope
+	// QosFlows are all QoS flows of the UE
+	QosFlows   []policyAPI.QosID
+	Candidates []Candidate
+	// Policies are the enforced policies applicable to the UE with any of its QoS flows,
+	// ordered from the highest to the lowest precedence
+	Policies []*mho.PolicyData
+}
+
+// Decision is the outcome of a steering algorithm. Target is nil when the UE should stay in its cell.
+// Scores, keyed by the candidate CGI, let the handover hysteresis and time-to-trigger be applied;
+// without the score of the serving cell the handover is triggered at once.
+type Decision struct {
+	Target *Candidate
+	Reason string
+	Scores map[string]float64
+}
+
+// SteeringAlgorithm chooses the cell the UE should be served by.
+type SteeringAlgorithm interface {
+	Name() string
+	Decide(input Input) Decision
+}
+
+// PolicyScorer scores the candidate cells by the policies applicable to the UE and the configured scoring.
+type PolicyScorer interface {
+	// GetTsResultForPoliciesV2 returns the best scored allowed cell, the scores of all cells and whether any cell is allowed
+	GetTsResultForPoliciesV2(ueScope policyAPI.Scope, matching []*mho.PolicyData, rsrps []int, loads []float64, cellIds []policyAPI.CellID) (policyAPI.CellID, []float64, bool)
+	// GetPreferenceFromPoliciesV2 returns the preference the already matched policies assign to the cell
+	GetPreferenceFromPoliciesV2(policies []*mho.PolicyData, queryCellId policyAPI.CellID) string
+}
+
+var _ PolicyScorer = &policy.PolicyManager{}
+
+// Factory creates the algorithm with the parameters from the configuration.
+type Factory func(scorer PolicyScorer, parameters map[string]interface{}) (SteeringAlgorithm, error)
+
+var (
+	registry   = make(map[string]Factory)
+	registryMu sync.RWMutex
+)
+
+// Register makes the algorithm selectable by name from the configuration; it is meant to be called from init().
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[name]; ok {
+		log.Warnf("Steering algorithm %v registered twice, replacing it", name)
+	}
+	registry[name] = factory
+}
+
+// NewAlgorithm creates the registered algorithm with the given name.
+func NewAlgorithm(name string, scorer PolicyScorer, parameters map[string]interface{}) (SteeringAlgorithm, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown steering algorithm %v, available: %v", name, GetAlgorithmNames())
+	}
+	return factory(scorer, parameters)
+}
+
+func GetAlgorithmNames() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
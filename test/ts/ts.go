@@ -61,6 +61,7 @@ func (s *TestSuite) TestTsSm(t *testing.T) {
 		SMName:             "oran-e2sm-mho",
 		SMVersion:          "v2",
 		TSPolicySchemePath: "/data/schemas/ORAN_TrafficSteeringPreference_v102.json",
+		ConfigPath:         "/tmp/config.json",
 	}
 
 	a1Config := a1.Config{
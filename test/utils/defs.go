@@ -35,8 +35,8 @@ const (
 			 "cellIdList":[
 				{
 				   "plmnId":{
-					  "mcc":"138",
-					  "mnc":"426"
+					  "mcc":"314",
+					  "mnc":"628"
 				   },
 				   "cId":{
 					  "ncI":470106432